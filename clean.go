@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/han-nwin/dusty/cleaner"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// runClean is the clean subcommand: it cleans the given paths without the
+// TUI, under the same safety checks, and reports on each. It returns the
+// exit status.
+func runClean(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dusty clean [-trash | -dry-run] [-x] PATH...")
+		fs.PrintDefaults()
+	}
+	trash := fs.Bool("trash", false, "move to the trash instead of deleting")
+	dryRun := fs.Bool("dry-run", false, "remove nothing, only report what would be freed")
+	oneFS := fs.Bool("x", false, "refuse paths that are or contain mount points")
+	var projects []string
+	fs.Func("projects", "allow build artifacts found in `dir` (repeatable)", func(dir string) error {
+		projects = append(projects, dir)
+		return nil
+	})
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || (*trash && *dryRun) {
+		fs.Usage()
+		return 2
+	}
+	s, err := scanner.NewScanner()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	s.OneFileSystem = *oneFS
+	s.Projects = projects
+
+	c := cleaner.Cleaner{Strategy: cleaner.Delete, Check: s.CheckCleanablePath}
+	switch {
+	case *trash:
+		c.Strategy = cleaner.Trash
+	case *dryRun:
+		c.Strategy = cleaner.DryRun
+	}
+
+	// Clean around excluded items, as the TUI does
+	start := time.Now()
+	report := &cleaner.Report{}
+	var paths []string
+	for _, arg := range fs.Args() {
+		path, err := filepath.Abs(arg)
+		var cleanable []string
+		if err == nil {
+			cleanable, err = s.CleanablePaths(path)
+		}
+		if err != nil {
+			report.Add(cleaner.Result{Path: arg, Err: err})
+			continue
+		}
+		paths = append(paths, cleanable...)
+	}
+	report.Merge(c.Clean(ctx, paths))
+	report.Duration = time.Since(start)
+
+	printReport(report, c.Strategy)
+	if len(report.Failed()) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printReport prints a line per path and then the totals
+func printReport(r *cleaner.Report, strategy cleaner.Strategy) {
+	for _, res := range r.Results {
+		if res.Err != nil {
+			fmt.Printf("failed  %10s  %v\n", "", res.Err)
+			continue
+		}
+		fmt.Printf("ok      %10s  %s (%s)\n", scanner.FormatSize(res.Freed+res.Moved), res.Path, res.Duration.Round(time.Millisecond))
+	}
+
+	verb, size, where := "Freed", r.Freed(), ""
+	switch strategy {
+	case cleaner.DryRun:
+		verb = "Would free"
+	case cleaner.Trash:
+		verb, size, where = "Moved", r.Moved(), " to the trash"
+	}
+	fmt.Printf("%s %s%s from %d of %d paths in %s\n", verb, scanner.FormatSize(size), where,
+		len(r.Results)-len(r.Failed()), len(r.Results), r.Duration.Round(time.Millisecond))
+}
@@ -0,0 +1,186 @@
+// Package cleaner removes paths with an interchangeable strategy, such as
+// moving them to the trash, and reports what happened to each one
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Strategy is how a path is removed
+type Strategy interface {
+	Name() string
+	Remove(path string) error
+}
+
+// The strategies
+var (
+	Delete Strategy = deleteStrategy{}
+	Trash  Strategy = trashStrategy{}
+	DryRun Strategy = dryRun{}
+)
+
+// deleteStrategy removes paths for good
+type deleteStrategy struct{}
+
+func (deleteStrategy) Name() string { return "delete" }
+
+func (deleteStrategy) Remove(path string) error { return os.RemoveAll(path) }
+
+// dryRun removes nothing, to see what the others would free
+type dryRun struct{}
+
+func (dryRun) Name() string { return "dry-run" }
+
+func (dryRun) Remove(string) error { return nil }
+
+// Result is what happened to one path
+type Result struct {
+	Path     string
+	Freed    int64 // On-disk bytes freed, or that would be with DryRun
+	Moved    int64 // On-disk bytes moved to the trash, still in use until it's emptied
+	Duration time.Duration
+	Err      error // Nil if the path was cleaned
+}
+
+// Report is what a cleaning run did to each of its paths
+type Report struct {
+	Results  []Result
+	Duration time.Duration
+}
+
+// Add records the result for one more path
+func (r *Report) Add(res Result) {
+	r.Results = append(r.Results, res)
+}
+
+// Merge adds o's results to r
+func (r *Report) Merge(o *Report) {
+	if o == nil {
+		return
+	}
+	r.Results = append(r.Results, o.Results...)
+}
+
+// Freed returns the bytes freed across every path
+func (r *Report) Freed() int64 {
+	var freed int64
+	for _, res := range r.Results {
+		freed += res.Freed
+	}
+	return freed
+}
+
+// Moved returns the bytes moved to the trash across every path
+func (r *Report) Moved() int64 {
+	var moved int64
+	for _, res := range r.Results {
+		moved += res.Moved
+	}
+	return moved
+}
+
+// Failed returns the results of the paths that couldn't be cleaned
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of every path that failed, nil if none did
+func (r *Report) Err() error {
+	var errs []error
+	for _, res := range r.Failed() {
+		errs = append(errs, res.Err)
+	}
+	return errors.Join(errs...)
+}
+
+// Cleaner removes paths with Strategy. A path that fails doesn't stop the
+// others.
+type Cleaner struct {
+	Strategy Strategy
+	Check    func(path string) error // Refuses a path before it's touched; nil allows every path
+}
+
+// Clean removes each path in turn and reports on every one. Once ctx is
+// done the remaining paths fail with its error.
+func (c Cleaner) Clean(ctx context.Context, paths []string) *Report {
+	start := time.Now()
+	report := &Report{}
+	seen := make(map[fileID]bool)
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			report.Add(Result{Path: path, Err: err})
+			continue
+		}
+		report.Add(c.clean(path, seen))
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (c Cleaner) clean(path string, seen map[fileID]bool) Result {
+	start := time.Now()
+	res := Result{Path: path}
+	defer func() { res.Duration = time.Since(start) }()
+
+	if c.Check != nil {
+		if err := c.Check(path); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+	size, err := diskUsage(path, seen)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to clean %s: %v", path, err)
+		return res
+	}
+	if err := c.Strategy.Remove(path); err != nil {
+		res.Err = fmt.Errorf("failed to clean %s: %v", path, err)
+		return res
+	}
+	if c.Strategy == Trash {
+		res.Moved = size // Nothing is freed until the trash is emptied
+	} else {
+		res.Freed = size
+	}
+	return res
+}
+
+// diskUsage returns the on-disk bytes below path, symlinks not followed.
+// Files already in seen, hard links to one counted before, count nothing.
+// A missing path uses no space.
+func diskUsage(path string, seen map[fileID]bool) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // Gone meanwhile
+		}
+		if id, ok := identity(info); ok {
+			if seen[id] {
+				return nil
+			}
+			seen[id] = true
+		}
+		total += allocated(info)
+		return nil
+	})
+	return total, err
+}
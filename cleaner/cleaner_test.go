@@ -0,0 +1,147 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// makeTree creates dir with two files in it, and returns dir
+func makeTree(t *testing.T, dir string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "sub/b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 10000), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func TestCleanKeepsGoingPastFailures(t *testing.T) {
+	dir := t.TempDir()
+	first := makeTree(t, filepath.Join(dir, "first"))
+	refused := makeTree(t, filepath.Join(dir, "refused"))
+	last := makeTree(t, filepath.Join(dir, "last"))
+
+	c := Cleaner{Strategy: Delete, Check: func(path string) error {
+		if path == refused {
+			return errors.New("refusing to clean " + path)
+		}
+		return nil
+	}}
+	r := c.Clean(context.Background(), []string{first, refused, last})
+
+	if len(r.Results) != 3 {
+		t.Fatalf("results = %+v, want one per path", r.Results)
+	}
+	if exists(first) || exists(last) || !exists(refused) {
+		t.Errorf("first, refused, last exist = %v, %v, %v; want only refused left",
+			exists(first), exists(refused), exists(last))
+	}
+	for _, res := range []Result{r.Results[0], r.Results[2]} {
+		if res.Err != nil || res.Freed < 20000 {
+			t.Errorf("%s: freed %d, err %v; want at least 20000 freed", res.Path, res.Freed, res.Err)
+		}
+	}
+	failed := r.Failed()
+	if len(failed) != 1 || failed[0].Path != refused || failed[0].Freed != 0 {
+		t.Errorf("failed = %+v, want just %s", failed, refused)
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "refusing to clean") {
+		t.Errorf("Err = %v, want the refusal", err)
+	}
+	if r.Freed() != r.Results[0].Freed+r.Results[2].Freed {
+		t.Errorf("Freed = %d, want the sum of the results", r.Freed())
+	}
+}
+
+func TestDryRunRemovesNothing(t *testing.T) {
+	dir := makeTree(t, filepath.Join(t.TempDir(), "cache"))
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	r := Cleaner{Strategy: DryRun}.Clean(context.Background(), []string{dir, missing})
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(filepath.Join(dir, "sub", "b")) {
+		t.Error("a dry run removed files")
+	}
+	if r.Results[0].Freed < 20000 || r.Results[1].Freed != 0 {
+		t.Errorf("freed = %d, %d; want the tree's size, then nothing for the missing path",
+			r.Results[0].Freed, r.Results[1].Freed)
+	}
+}
+
+func TestHardLinksCountOnce(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("hard links are only told apart on Linux and macOS")
+	}
+	dir := t.TempDir()
+	a := makeTree(t, filepath.Join(dir, "a"))
+	b := filepath.Join(dir, "b")
+	if err := os.Mkdir(b, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(a, "a"), filepath.Join(b, "a")); err != nil {
+		t.Skip(err)
+	}
+
+	r := Cleaner{Strategy: DryRun}.Clean(context.Background(), []string{a, b})
+	if r.Results[1].Freed >= 10000 {
+		t.Errorf("b freed %d, want its file counted with a", r.Results[1].Freed)
+	}
+}
+
+func TestCanceledCleanStops(t *testing.T) {
+	dir := makeTree(t, filepath.Join(t.TempDir(), "cache"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := Cleaner{Strategy: Delete}.Clean(ctx, []string{dir})
+	if !errors.Is(r.Err(), context.Canceled) || !exists(dir) {
+		t.Errorf("Err = %v, exists = %v; want nothing cleaned", r.Err(), exists(dir))
+	}
+}
+
+func TestTrashFreedesktop(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("uses the freedesktop.org trash")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	first := makeTree(t, filepath.Join(dir, "one", "cache"))
+	second := makeTree(t, filepath.Join(dir, "two", "cache"))
+
+	r := Cleaner{Strategy: Trash}.Clean(context.Background(), []string{first, second})
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	trash := filepath.Join(dir, "data", "Trash")
+	for _, name := range []string{"cache", "cache.2"} {
+		if !exists(filepath.Join(trash, "files", name, "sub", "b")) {
+			t.Errorf("%s isn't in the trash", name)
+		}
+	}
+	info, err := os.ReadFile(filepath.Join(trash, "info", "cache.2.trashinfo"))
+	if err != nil || !strings.Contains(string(info), "Path="+second+"\n") {
+		t.Errorf("trashinfo = %q, %v; want the original path", info, err)
+	}
+	if exists(first) || exists(second) {
+		t.Error("trashed paths are still in place")
+	}
+	if r.Freed() != 0 || r.Moved() < 40000 {
+		t.Errorf("Freed = %d, Moved = %d; want nothing freed and both trees moved", r.Freed(), r.Moved())
+	}
+}
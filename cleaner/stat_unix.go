@@ -0,0 +1,32 @@
+//go:build darwin || linux
+
+package cleaner
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file across its hard links
+type fileID struct {
+	dev, ino uint64
+}
+
+// allocated returns the bytes a file occupies on disk
+func allocated(info os.FileInfo) int64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	return int64(st.Blocks) * 512
+}
+
+// identity returns the file's device and inode if it has other hard links;
+// only those need telling apart
+func identity(info os.FileInfo) (fileID, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || info.IsDir() || st.Nlink < 2 {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
@@ -0,0 +1,17 @@
+package cleaner
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// trashStrategy moves paths to the Finder's Trash
+type trashStrategy struct{}
+
+func (trashStrategy) Name() string { return "trash" }
+
+func (trashStrategy) Remove(path string) error {
+	// Move to trash using AppleScript
+	script := fmt.Sprintf(`tell app "Finder" to delete POSIX file "%s"`, path)
+	return exec.Command("osascript", "-e", script).Run()
+}
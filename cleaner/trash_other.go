@@ -0,0 +1,14 @@
+//go:build !unix
+
+package cleaner
+
+import "errors"
+
+// trashStrategy isn't available off Unix
+type trashStrategy struct{}
+
+func (trashStrategy) Name() string { return "trash" }
+
+func (trashStrategy) Remove(string) error {
+	return errors.New("moving to the trash isn't supported on this platform; delete instead")
+}
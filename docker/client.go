@@ -0,0 +1,185 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Client talks to a Docker-compatible Engine API, Docker's or Podman's, over
+// its unix socket
+type Client struct {
+	Socket string
+	http   *http.Client
+}
+
+// NewClient returns a client for the Engine API listening on socket
+func NewClient(socket string) *Client {
+	return &Client{
+		Socket: socket,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// sockets lists where the Engine API usually listens: $DOCKER_HOST if it's
+// a unix socket, Docker's system and per-user sockets, then Podman's
+func sockets(home string) []string {
+	var paths []string
+	if host, ok := strings.CutPrefix(os.Getenv("DOCKER_HOST"), "unix://"); ok {
+		paths = append(paths, host)
+	}
+	paths = append(paths, "/var/run/docker.sock", filepath.Join(home, ".docker", "run", "docker.sock"))
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		paths = append(paths, filepath.Join(runtime, "podman", "podman.sock"))
+	}
+	return paths
+}
+
+// FindSocket returns the first Engine API socket that answers, or "" if
+// there's none
+func FindSocket(ctx context.Context, home string) string {
+	for _, path := range sockets(home) {
+		info, err := os.Stat(path)
+		if err != nil || info.Mode()&os.ModeSocket == 0 {
+			continue
+		}
+		if NewClient(path).Ping(ctx) == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// apiError is the body of an Engine API error response
+type apiError struct {
+	Message string `json:"message"`
+}
+
+// do sends a request and decodes a JSON response into out, if given
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, out any) error {
+	u := "http://docker" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var e apiError
+		if json.Unmarshal(body, &e) == nil && e.Message != "" {
+			return fmt.Errorf("%s %s: %s", method, path, e.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// Ping checks that the daemon is up, giving it a couple of seconds
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return c.do(ctx, http.MethodGet, "/_ping", nil, nil)
+}
+
+// DiskUsage is the part of GET /system/df dusty needs
+type DiskUsage struct {
+	Images []struct {
+		ID         string   `json:"Id"`
+		RepoTags   []string `json:"RepoTags"`
+		Created    int64    `json:"Created"` // Unix seconds
+		Size       int64    `json:"Size"`
+		SharedSize int64    `json:"SharedSize"` // -1 if unknown
+		Containers int64    `json:"Containers"` // -1 if unknown
+	} `json:"Images"`
+	Containers []struct {
+		ID      string   `json:"Id"`
+		Names   []string `json:"Names"`
+		Image   string   `json:"Image"`
+		Created int64    `json:"Created"` // Unix seconds
+		SizeRw  int64    `json:"SizeRw"`
+		State   string   `json:"State"`
+	} `json:"Containers"`
+	Volumes []struct {
+		Name      string `json:"Name"`
+		CreatedAt string `json:"CreatedAt"`
+		UsageData *struct {
+			Size     int64 `json:"Size"`     // -1 if unknown
+			RefCount int64 `json:"RefCount"` // -1 if unknown
+		} `json:"UsageData"`
+	} `json:"Volumes"`
+	BuildCache []struct {
+		ID          string `json:"ID"`
+		Type        string `json:"Type"`
+		Description string `json:"Description"`
+		InUse       bool   `json:"InUse"`
+		Size        int64  `json:"Size"`
+		LastUsedAt  string `json:"LastUsedAt"`
+	} `json:"BuildCache"`
+}
+
+// DiskUsage reports what the daemon stores and how big it is
+func (c *Client) DiskUsage(ctx context.Context) (*DiskUsage, error) {
+	var du DiskUsage
+	if err := c.do(ctx, http.MethodGet, "/system/df", nil, &du); err != nil {
+		return nil, err
+	}
+	return &du, nil
+}
+
+// RemoveImage deletes an image that no container uses
+func (c *Client) RemoveImage(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/images/"+url.PathEscape(id), nil, nil)
+}
+
+// RemoveContainer deletes a stopped container, keeping its volumes
+func (c *Client) RemoveContainer(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id), nil, nil)
+}
+
+// RemoveVolume deletes a volume no container refers to
+func (c *Client) RemoveVolume(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), nil, nil)
+}
+
+// PruneBuildCache deletes the given build cache records and returns the
+// bytes reclaimed
+func (c *Client) PruneBuildCache(ctx context.Context, ids []string) (int64, error) {
+	filters, err := json.Marshal(map[string][]string{"id": ids})
+	if err != nil {
+		return 0, err
+	}
+	var out struct {
+		SpaceReclaimed int64 `json:"SpaceReclaimed"`
+	}
+	err = c.do(ctx, http.MethodPost, "/build/prune", url.Values{"filters": {string(filters)}}, &out)
+	return out.SpaceReclaimed, err
+}
@@ -0,0 +1,271 @@
+// Package docker exposes container storage as a dusty target: dangling
+// images, stopped containers, unused volumes and build cache, read from and
+// pruned through the Engine API of Docker or Podman.
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// Root is the path of the Docker entry. Entries below it are Root followed
+// by their kind and ID, like "docker:images/sha256:…"; none of them are
+// filesystem paths.
+const Root = "docker:"
+
+// Kinds of items, the second part of their paths
+const (
+	KindImages     = "images"
+	KindContainers = "containers"
+	KindVolumes    = "volumes"
+	KindBuildCache = "buildcache"
+)
+
+// kinds are the groups under Root, in display order
+var kinds = []struct {
+	kind, name, risk string
+}{
+	{KindImages, "Dangling images", "Images are pulled or rebuilt again when needed"},
+	{KindContainers, "Stopped containers", "Their logs and changes to their filesystem are gone"},
+	{KindVolumes, "Unused volumes", "The data in the volumes is deleted for good"},
+	{KindBuildCache, "Build cache", "The next image builds are slower"},
+}
+
+// Daemon is a running Docker or Podman daemon, reached through an Engine
+// API client
+type Daemon struct {
+	Client *Client
+}
+
+// Find returns the daemon behind the first Engine API socket that answers,
+// or nil if none is running
+func Find(ctx context.Context, home string) *Daemon {
+	socket := FindSocket(ctx, home)
+	if socket == "" {
+		return nil
+	}
+	return &Daemon{Client: NewClient(socket)}
+}
+
+// Owns reports whether path is a Docker entry rather than a file
+func Owns(path string) bool {
+	return strings.HasPrefix(path, Root)
+}
+
+// target describes the Docker entry
+var target = scanner.Target{
+	Path:        Root,
+	Description: "Docker / Podman",
+	Category:    scanner.CategoryDeveloper,
+	Risk:        "Unused images, containers, volumes and build cache are removed by the daemon",
+}
+
+// Scan lists what the daemon could reclaim as a tree: the Docker entry, a
+// child per kind of item and the items below those. Images still used by a
+// container, running containers, volumes in use and build cache in use are
+// left out.
+func (m *Daemon) Scan(ctx context.Context) (*scanner.CacheEntry, error) {
+	du, err := m.Client.DiskUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	items := make(map[string][]*scanner.CacheEntry)
+	add := func(kind, id, name string, size, shared int64, mod time.Time) {
+		e := &scanner.CacheEntry{
+			Name:       name,
+			Path:       Root + kind + "/" + id,
+			Size:       size,
+			AllocSize:  size,
+			SharedSize: max(shared, 0),
+			FileCount:  1,
+			LastMod:    mod,
+			OldestMod:  mod,
+			Depth:      2,
+		}
+		e.Ages.Add(mod, now, size)
+		items[kind] = append(items[kind], e)
+	}
+
+	for _, img := range du.Images {
+		if !dangling(img.RepoTags) || img.Containers != 0 {
+			continue
+		}
+		add(KindImages, img.ID, shortID(img.ID), img.Size, img.SharedSize, time.Unix(img.Created, 0))
+	}
+	for _, c := range du.Containers {
+		if c.State != "exited" && c.State != "created" && c.State != "dead" {
+			continue
+		}
+		name := shortID(c.ID)
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		add(KindContainers, c.ID, name, c.SizeRw, 0, time.Unix(c.Created, 0))
+	}
+	for _, v := range du.Volumes {
+		if v.UsageData == nil || v.UsageData.RefCount != 0 || v.UsageData.Size < 0 {
+			continue
+		}
+		add(KindVolumes, v.Name, shortID(v.Name), v.UsageData.Size, 0, parseTime(v.CreatedAt))
+	}
+	for _, b := range du.BuildCache {
+		if b.InUse {
+			continue
+		}
+		name := b.Description
+		if name == "" {
+			name = b.Type + " " + shortID(b.ID)
+		}
+		add(KindBuildCache, b.ID, name, b.Size, 0, parseTime(b.LastUsedAt))
+	}
+
+	root := &scanner.CacheEntry{
+		Name:        "Docker",
+		Path:        Root,
+		Description: target.Description,
+		Category:    target.Category,
+		Risk:        target.Risk,
+		IsParent:    true,
+		IsDir:       true,
+		Listed:      true,
+	}
+	for _, k := range kinds {
+		if len(items[k.kind]) == 0 {
+			continue
+		}
+		group := &scanner.CacheEntry{
+			Name:        k.name,
+			Path:        Root + k.kind,
+			Description: k.name,
+			Risk:        k.risk,
+			IsDir:       true,
+			Listed:      true,
+			Depth:       1,
+		}
+		children := items[k.kind]
+		sort.SliceStable(children, func(i, j int) bool { return children[i].Size > children[j].Size })
+		for _, e := range children {
+			e.Risk = k.risk
+			addTotals(group, e)
+		}
+		group.Children = children
+		root.Children = append(root.Children, group)
+		addTotals(root, group)
+	}
+	return root, nil
+}
+
+// addTotals adds child's sizes and ages to e
+func addTotals(e, child *scanner.CacheEntry) {
+	e.Size += child.Size
+	e.AllocSize += child.AllocSize
+	e.SharedSize += child.SharedSize
+	e.FileCount += child.FileCount
+	e.Ages.Merge(&child.Ages)
+	if child.LastMod.After(e.LastMod) {
+		e.LastMod = child.LastMod
+	}
+	if e.OldestMod.IsZero() || child.OldestMod.Before(e.OldestMod) {
+		e.OldestMod = child.OldestMod
+	}
+}
+
+// Older counts the items at or below e created or last used before cutoff,
+// every item if cutoff is zero
+func Older(e *scanner.CacheEntry, cutoff time.Time) scanner.AgeMatch {
+	var match scanner.AgeMatch
+	for _, item := range leaves(e, cutoff) {
+		match.Files++
+		match.Size += item.Size
+		match.AllocSize += item.AllocSize - item.SharedSize
+	}
+	return match
+}
+
+// Clean removes the items at or below e through the daemon, only those
+// older than cutoff unless it's zero, and returns the bytes freed. It keeps
+// going past items the daemon refuses to remove, such as an image a new
+// container started using, and reports them all.
+func (m *Daemon) Clean(ctx context.Context, e *scanner.CacheEntry, cutoff time.Time) (int64, error) {
+	var freed int64
+	var errs []error
+	var buildCache []string
+	for _, item := range leaves(e, cutoff) {
+		kind, id, _ := strings.Cut(strings.TrimPrefix(item.Path, Root), "/")
+		var err error
+		switch kind {
+		case KindImages:
+			err = m.Client.RemoveImage(ctx, id)
+		case KindContainers:
+			err = m.Client.RemoveContainer(ctx, id)
+		case KindVolumes:
+			err = m.Client.RemoveVolume(ctx, id)
+		case KindBuildCache:
+			buildCache = append(buildCache, id)
+			continue
+		default:
+			err = fmt.Errorf("unknown Docker item %s", item.Path)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		freed += item.AllocSize - item.SharedSize
+	}
+	if len(buildCache) > 0 {
+		reclaimed, err := m.Client.PruneBuildCache(ctx, buildCache)
+		freed += reclaimed
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return freed, errors.Join(errs...)
+}
+
+// leaves returns the items at or below e older than cutoff, unless it's
+// zero
+func leaves(e *scanner.CacheEntry, cutoff time.Time) []*scanner.CacheEntry {
+	if !strings.Contains(e.Path, "/") {
+		// Root or a group
+		var items []*scanner.CacheEntry
+		for _, child := range e.Children {
+			items = append(items, leaves(child, cutoff)...)
+		}
+		return items
+	}
+	if !cutoff.IsZero() && !e.LastMod.Before(cutoff) {
+		return nil
+	}
+	return []*scanner.CacheEntry{e}
+}
+
+// dangling reports whether an image has no tags
+func dangling(tags []string) bool {
+	for _, t := range tags {
+		if t != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+// shortID shortens a content digest to the 12 characters Docker shows
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
@@ -0,0 +1,319 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/han-nwin/dusty/cleaner"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// fakeDaemon serves the Engine API endpoints the module uses on a unix
+// socket, recording the removals it's asked for
+type fakeDaemon struct {
+	socket string
+	df     string // Body of GET /system/df
+
+	mu      sync.Mutex
+	pings   int
+	removed []string // "METHOD path", in order
+	refuse  map[string]string
+}
+
+const fakeDF = `{
+  "Images": [
+    {"Id": "sha256:aaaaaaaaaaaaaaaaaaaa", "RepoTags": ["<none>:<none>"], "Created": 1700000000, "Size": 5000, "SharedSize": 1000, "Containers": 0},
+    {"Id": "sha256:bbbbbbbbbbbbbbbbbbbb", "RepoTags": ["app:latest"], "Created": 1700000000, "Size": 9000, "SharedSize": 0, "Containers": 0},
+    {"Id": "sha256:cccccccccccccccccccc", "RepoTags": null, "Created": 1700000000, "Size": 7000, "SharedSize": 0, "Containers": 1}
+  ],
+  "Containers": [
+    {"Id": "c1c1c1c1c1c1c1c1", "Names": ["/old_job"], "Created": 1700000000, "SizeRw": 300, "State": "exited"},
+    {"Id": "c2c2c2c2c2c2c2c2", "Names": ["/web"], "Created": 1700000000, "SizeRw": 800, "State": "running"}
+  ],
+  "Volumes": [
+    {"Name": "orphan", "CreatedAt": "2023-11-14T22:13:20Z", "UsageData": {"Size": 2000, "RefCount": 0}},
+    {"Name": "db", "CreatedAt": "2023-11-14T22:13:20Z", "UsageData": {"Size": 4000, "RefCount": 1}}
+  ],
+  "BuildCache": [
+    {"ID": "bc1", "Type": "regular", "Description": "RUN make", "InUse": false, "Size": 600, "LastUsedAt": "2023-11-14T22:13:20Z"},
+    {"ID": "bc2", "Type": "regular", "Description": "RUN go build", "InUse": true, "Size": 900, "LastUsedAt": "2023-11-14T22:13:20Z"}
+  ]
+}`
+
+func newFakeDaemon(t *testing.T) *fakeDaemon {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, too few for some
+	// t.TempDir paths
+	dir, err := os.MkdirTemp("", "dusty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	d := &fakeDaemon{
+		socket: filepath.Join(dir, "docker.sock"),
+		df:     fakeDF,
+		refuse: make(map[string]string),
+	}
+	l, err := net.Listen("unix", d.socket)
+	if err != nil {
+		t.Skipf("unix sockets unsupported: %v", err)
+	}
+	srv := &http.Server{Handler: d}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return d
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/_ping":
+		d.mu.Lock()
+		d.pings++
+		d.mu.Unlock()
+		w.Write([]byte("OK"))
+	case r.Method == http.MethodGet && r.URL.Path == "/system/df":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(d.df))
+	case r.Method == http.MethodDelete || r.Method == http.MethodPost && r.URL.Path == "/build/prune":
+		call := r.Method + " " + r.URL.Path
+		if r.URL.Path == "/build/prune" {
+			call += " " + r.URL.Query().Get("filters")
+		}
+		d.mu.Lock()
+		d.removed = append(d.removed, call)
+		msg, refused := d.refuse[r.URL.Path]
+		d.mu.Unlock()
+		if refused {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(apiError{Message: msg})
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"SpaceReclaimed": 600}`))
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *fakeDaemon) calls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.removed)
+}
+
+func (d *fakeDaemon) daemon() *Daemon {
+	return &Daemon{Client: NewClient(d.socket)}
+}
+
+// find returns the entry at path below e
+func find(e *scanner.CacheEntry, path string) *scanner.CacheEntry {
+	if e.Path == path {
+		return e
+	}
+	for _, child := range e.Children {
+		if found := find(child, path); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestFindSocket(t *testing.T) {
+	d := newFakeDaemon(t)
+	t.Setenv("DOCKER_HOST", "unix://"+d.socket)
+	m := Find(context.Background(), t.TempDir())
+	if m == nil || m.Client.Socket != d.socket {
+		t.Fatalf("Find = %v, want the fake daemon at %s", m, d.socket)
+	}
+}
+
+func TestModulePingsOncePerScan(t *testing.T) {
+	d := newFakeDaemon(t)
+	t.Setenv("DOCKER_HOST", "unix://"+d.socket)
+	ctx := context.Background()
+	s := &scanner.Scanner{HomeDir: t.TempDir()}
+	m := &module{}
+	if !m.Available(ctx, s) {
+		t.Fatal("module unavailable with the fake daemon running")
+	}
+	if _, err := m.Scan(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pings != 1 {
+		t.Errorf("daemon pinged %d times, want 1", d.pings)
+	}
+}
+
+func TestScanListsOnlyUnused(t *testing.T) {
+	d := newFakeDaemon(t)
+	root, err := d.daemon().Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, group := range root.Children {
+		for _, item := range group.Children {
+			got = append(got, item.Path)
+		}
+	}
+	want := []string{
+		"docker:images/sha256:aaaaaaaaaaaaaaaaaaaa",
+		"docker:containers/c1c1c1c1c1c1c1c1",
+		"docker:volumes/orphan",
+		"docker:buildcache/bc1",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("items = %q, want %q", got, want)
+	}
+	if root.Size != 5000+300+2000+600 {
+		t.Errorf("root size = %d, want %d", root.Size, 5000+300+2000+600)
+	}
+	if root.FileCount != 4 {
+		t.Errorf("root count = %d, want 4", root.FileCount)
+	}
+	if c := find(root, "docker:containers/c1c1c1c1c1c1c1c1"); c == nil || c.Name != "old_job" {
+		t.Errorf("container = %+v, want it named old_job", c)
+	}
+	if img := find(root, "docker:images/sha256:aaaaaaaaaaaaaaaaaaaa"); img.SharedSize != 1000 {
+		t.Errorf("image shared size = %d, want 1000", img.SharedSize)
+	}
+}
+
+func TestModuleRefusesTrash(t *testing.T) {
+	d := newFakeDaemon(t)
+	t.Setenv("DOCKER_HOST", "unix://"+d.socket)
+	root, err := d.daemon().Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &scanner.Scanner{HomeDir: t.TempDir()}
+	r := (&module{}).Clean(context.Background(), s, []*scanner.CacheEntry{root}, scanner.CleanOptions{Strategy: cleaner.Trash})
+	if !errors.Is(r.Err(), scanner.ErrNoTrash) || r.Freed() != 0 {
+		t.Errorf("trashing: err = %v, freed %d; want ErrNoTrash and nothing freed", r.Err(), r.Freed())
+	}
+	if got := d.calls(); len(got) != 0 {
+		t.Errorf("calls = %q, want nothing pruned", got)
+	}
+}
+
+func TestCleanRemovesItemsBelowEntry(t *testing.T) {
+	d := newFakeDaemon(t)
+	m := d.daemon()
+	root, err := m.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	freed, err := m.Clean(context.Background(), root, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"DELETE /images/sha256:aaaaaaaaaaaaaaaaaaaa",
+		"DELETE /containers/c1c1c1c1c1c1c1c1",
+		"DELETE /volumes/orphan",
+		`POST /build/prune {"id":["bc1"]}`,
+	}
+	if got := d.calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+	// The image's shared layers stay; the build cache reports its own
+	if freed != 4000+300+2000+600 {
+		t.Errorf("freed = %d, want %d", freed, 4000+300+2000+600)
+	}
+}
+
+func TestCleanOnlyOlderItems(t *testing.T) {
+	d := newFakeDaemon(t)
+	m := d.daemon()
+	root, err := m.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Everything in the fixture dates from November 2023
+	cutoff := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if match := Older(root, cutoff); match.Files != 0 {
+		t.Errorf("Older = %+v, want nothing", match)
+	}
+	if _, err := m.Clean(context.Background(), root, cutoff); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.calls(); len(got) != 0 {
+		t.Errorf("calls = %q, want none", got)
+	}
+}
+
+func TestCleanReportsRefusals(t *testing.T) {
+	d := newFakeDaemon(t)
+	d.refuse["/volumes/orphan"] = "volume is in use - [c3]"
+	m := d.daemon()
+	root, err := m.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	freed, err := m.Clean(context.Background(), root, time.Time{})
+	if err == nil || !strings.Contains(err.Error(), "volume is in use") {
+		t.Errorf("err = %v, want the daemon's message", err)
+	}
+	// The other items are still removed
+	if len(d.calls()) != 4 {
+		t.Errorf("calls = %q, want all 4 attempted", d.calls())
+	}
+	if freed != 4000+300+600 {
+		t.Errorf("freed = %d, want %d", freed, 4000+300+600)
+	}
+}
+
+func TestScanReportsDaemonErrors(t *testing.T) {
+	d := newFakeDaemon(t)
+	d.df = `{"Images": [`
+	if _, err := d.daemon().Scan(context.Background()); err == nil {
+		t.Error("Scan of a truncated response succeeded")
+	}
+
+	missing := &Daemon{Client: NewClient(filepath.Join(t.TempDir(), "none.sock"))}
+	if _, err := missing.Scan(context.Background()); err == nil {
+		t.Error("Scan without a daemon succeeded")
+	}
+}
+
+func TestScannerIncludesDocker(t *testing.T) {
+	d := newFakeDaemon(t)
+	t.Setenv("DOCKER_HOST", "unix://"+d.socket)
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+
+	s := &scanner.Scanner{HomeDir: t.TempDir(), Workers: 2}
+	result, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Path != Root {
+		t.Fatalf("entries = %+v, want the Docker entry alone", result.Entries)
+	}
+	if result.TotalSize != result.Entries[0].Size {
+		t.Errorf("total = %d, want %d", result.TotalSize, result.Entries[0].Size)
+	}
+
+	// Not part of the Browser profile
+	s.Profile = scanner.ProfileBrowser
+	if result, err = s.Scan(context.Background()); err != nil || len(result.Entries) != 0 {
+		t.Errorf("Browser profile = %+v, %v; want nothing", result.Entries, err)
+	}
+}
@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/han-nwin/dusty/cleaner"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+func init() {
+	scanner.Register(&module{})
+}
+
+// module makes the daemon's reclaimable storage a scanner module
+type module struct {
+	mu     sync.Mutex
+	socket string // Found by the last Available, so Scan doesn't search again
+}
+
+func (*module) Name() string { return "docker" }
+
+func (m *module) Available(ctx context.Context, s *scanner.Scanner) bool {
+	socket := FindSocket(ctx, s.HomeDir)
+	m.mu.Lock()
+	m.socket = socket
+	m.mu.Unlock()
+	return socket != ""
+}
+
+func (*module) Owns(path string) bool { return Owns(path) }
+
+func (m *module) Scan(ctx context.Context, s *scanner.Scanner) (*scanner.ScanResult, error) {
+	m.mu.Lock()
+	socket := m.socket
+	m.mu.Unlock()
+	if socket == "" {
+		socket = FindSocket(ctx, s.HomeDir)
+	}
+	if socket == "" {
+		return nil, errors.New("the daemon stopped answering")
+	}
+	d := &Daemon{Client: NewClient(socket)}
+	root, err := d.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &scanner.ScanResult{Entries: []*scanner.CacheEntry{root}}, nil
+}
+
+// Clean prunes the entries through the daemon unless it's a dry run.
+// Pruned items can't be restored, so cleaner.Trash is refused.
+func (*module) Clean(ctx context.Context, s *scanner.Scanner, entries []*scanner.CacheEntry, opts scanner.CleanOptions) *cleaner.Report {
+	if opts.Strategy == cleaner.Trash {
+		return scanner.RefuseTrash(entries)
+	}
+	start := time.Now()
+	report := &cleaner.Report{}
+	if opts.DryRun() {
+		for _, e := range entries {
+			report.Add(cleaner.Result{Path: e.Path, Freed: Older(e, cutoff(opts.Age)).AllocSize})
+		}
+		report.Duration = time.Since(start)
+		return report
+	}
+	d := Find(ctx, s.HomeDir)
+	for _, e := range entries {
+		began := time.Now()
+		res := cleaner.Result{Path: e.Path}
+		if d == nil {
+			res.Err = errors.New("docker: the daemon is no longer running")
+		} else {
+			var err error
+			if res.Freed, err = d.Clean(ctx, e, cutoff(opts.Age)); err != nil {
+				res.Err = fmt.Errorf("docker: %w", err)
+			}
+		}
+		res.Duration = time.Since(began)
+		report.Add(res)
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (*module) MatchOlderThan(_ context.Context, _ *scanner.Scanner, entries []*scanner.CacheEntry, f scanner.AgeFilter) (scanner.AgeMatch, error) {
+	var total scanner.AgeMatch
+	for _, e := range entries {
+		match := Older(e, cutoff(f))
+		total.Files += match.Files
+		total.Size += match.Size
+		total.AllocSize += match.AllocSize
+	}
+	return total, nil
+}
+
+// cutoff returns the time before which f selects items, zero when it's off.
+// Items are judged by when they were created or last used, whatever f's
+// basis.
+func cutoff(f scanner.AgeFilter) time.Time {
+	if !f.Active() {
+		return time.Time{}
+	}
+	return time.Now().Add(-f.OlderThan)
+}
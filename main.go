@@ -1,17 +1,106 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/han-nwin/dusty/scanner"
 	"github.com/han-nwin/dusty/ui"
+
+	// Target modules register themselves with the scanner
+	_ "github.com/han-nwin/dusty/docker"
+	_ "github.com/han-nwin/dusty/pkgmgr"
+	_ "github.com/han-nwin/dusty/plugins"
 )
 
+// subcommands run instead of the TUI, given their arguments, and return
+// the exit status
+var subcommands = map[string]func(context.Context, []string) int{
+	"scan":  runScan,
+	"clean": runClean,
+}
+
 func main() {
-	p := tea.NewProgram(ui.InitialModel())
-	if _, err := p.Run(); err != nil {
+	// Subcommands run without the TUI; SIGINT/SIGTERM cancel them too
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			code := run(ctx, os.Args[2:])
+			stop()
+			os.Exit(code)
+		}
+	}
+
+	var opts ui.Options
+	flag.BoolVar(&opts.OneFileSystem, "x", false, "stay on the filesystem of each target (same as -one-file-system)")
+	flag.BoolVar(&opts.OneFileSystem, "one-file-system", false, "stay on the filesystem of each target")
+	flag.StringVar(&opts.Profile, "profile", "", "profile to scan: Full, Developer, Browser or one from the config file")
+	flag.IntVar(&opts.OlderThanDays, "older-than", 0, "clean only files older than this many `days` inside selected entries")
+	flag.BoolVar(&opts.AccessTime, "atime", false, "judge -older-than by last access instead of last modification")
+	flag.BoolVar(&opts.NoCache, "no-cache", false, "read every directory instead of reusing sizes from the last scan")
+	flag.BoolVar(&opts.Watch, "watch", false, "keep sizes up to date as targets change (Linux)")
+	flag.Func("projects", "search `dir` for build artifacts like node_modules and target (repeatable)", func(dir string) error {
+		opts.Projects = append(opts.Projects, dir)
+		return nil
+	})
+	listModules := flag.Bool("modules", false, "list the target modules and whether each can run here, then exit")
+	flag.Parse()
+
+	if opts.OlderThanDays < 0 {
+		fmt.Println("Error: -older-than must not be negative")
+		os.Exit(2)
+	}
+
+	if *listModules {
+		if err := printModules(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if opts.Profile != "" {
+		s, err := scanner.NewScanner()
+		var p scanner.Profile
+		if err == nil {
+			p, err = s.FindProfile(opts.Profile)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		opts.Profile = p.Name
+	}
+
+	// SIGINT/SIGTERM stop the program (and with it any running scan)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	p := tea.NewProgram(ui.NewModel(opts), tea.WithContext(ctx))
+	if _, err := p.Run(); err != nil && !errors.Is(err, context.Canceled) {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// printModules lists every target module and whether it's available
+func printModules() error {
+	s, err := scanner.NewScanner()
+	if err != nil {
+		return err
+	}
+	for _, m := range scanner.Modules() {
+		status := "not available"
+		if m.Available(context.Background(), s) {
+			status = "available"
+		}
+		fmt.Printf("%-8s %s\n", m.Name(), status)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package pkgmgr exposes package manager caches as dusty targets that are
+// sized and cleaned by the tools themselves, like `npm cache clean` or
+// `go clean -cache`, rather than by deleting their files.
+package pkgmgr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/han-nwin/dusty/cleaner"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// Root prefixes the path of every entry here: a tool's entry is Root
+// followed by its name, like "tool:npm", and the items in its cache follow
+// after a slash. None of them are filesystem paths.
+const Root = "tool:"
+
+// ScanTimeout bounds each tool's dry run
+const ScanTimeout = time.Minute
+
+func init() {
+	for _, t := range tools {
+		scanner.Register(t)
+	}
+}
+
+func (t *tool) Name() string { return t.name }
+
+// Available reports whether the tool is on PATH
+func (t *tool) Available(context.Context, *scanner.Scanner) bool {
+	return t.lookPath() != ""
+}
+
+// Owns reports whether path is the tool's entry or one of its items
+func (t *tool) Owns(path string) bool {
+	root := Root + t.name
+	return path == root || strings.HasPrefix(path, root+"/")
+}
+
+// CleansWhole is always true: a tool cleans its whole cache or nothing
+func (t *tool) CleansWhole() bool { return true }
+
+// Scan runs the tool's dry run, bounded by ScanTimeout
+func (t *tool) Scan(ctx context.Context, s *scanner.Scanner) (*scanner.ScanResult, error) {
+	bin := t.lookPath()
+	if bin == "" {
+		return nil, fmt.Errorf("%s is not on PATH", t.name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, ScanTimeout)
+	defer cancel()
+	dir, items, err := t.scan(ctx, s, bin)
+	if err != nil {
+		return nil, err
+	}
+	e := t.entry(items)
+	e.Covers = dir
+	return &scanner.ScanResult{Entries: []*scanner.CacheEntry{e}}, nil
+}
+
+// entry builds the tool's entry from the items in its cache
+func (t *tool) entry(items []*scanner.CacheEntry) *scanner.CacheEntry {
+	e := &scanner.CacheEntry{
+		Name:        t.name,
+		Path:        Root + t.name,
+		Description: t.description,
+		Category:    scanner.CategoryDeveloper,
+		Risk:        t.risk,
+		IsParent:    true,
+		IsDir:       true,
+		Listed:      true,
+	}
+	for _, item := range items {
+		if item.Size <= 0 {
+			continue
+		}
+		item.Path = e.Path + "/" + item.Name
+		item.Risk = t.risk
+		item.Depth = 1
+		if item.AllocSize == 0 {
+			item.AllocSize = item.Size
+		}
+		e.Size += item.Size
+		e.AllocSize += item.AllocSize
+		e.FileCount += item.FileCount
+		e.Ages.Merge(&item.Ages)
+		if item.LastMod.After(e.LastMod) {
+			e.LastMod = item.LastMod
+		}
+		if !item.OldestMod.IsZero() && (e.OldestMod.IsZero() || item.OldestMod.Before(e.OldestMod)) {
+			e.OldestMod = item.OldestMod
+		}
+		e.Children = append(e.Children, item)
+	}
+	return e
+}
+
+// Clean runs the tool's cleaning command once, however many of its
+// entries are given, and reports them together. With an age cutoff only
+// files older than it go, which not every tool supports. A dry run runs
+// nothing, and cleaner.Trash is refused since the tool deletes for good.
+func (t *tool) Clean(ctx context.Context, _ *scanner.Scanner, entries []*scanner.CacheEntry, opts scanner.CleanOptions) *cleaner.Report {
+	if opts.Strategy == cleaner.Trash {
+		return scanner.RefuseTrash(entries)
+	}
+	start := time.Now()
+	report := &cleaner.Report{}
+	err := t.run(ctx, opts)
+	for _, e := range entries {
+		res := cleaner.Result{Path: e.Path, Err: err, Duration: time.Since(start)}
+		if err == nil && !opts.Age.Active() {
+			res.Freed = e.AllocSize - e.SharedSize // The tool doesn't say how much went by age
+		}
+		report.Add(res)
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+// run runs the tool's cleaning command for opts, nothing on a dry run
+func (t *tool) run(ctx context.Context, opts scanner.CleanOptions) error {
+	bin := t.lookPath()
+	if bin == "" {
+		return fmt.Errorf("%s is no longer on PATH", t.name)
+	}
+	args := t.clean
+	if opts.Age.Active() {
+		if t.prune == nil {
+			return fmt.Errorf("%s can't clean by age; turn off the age cutoff to clean it", t.name)
+		}
+		args = t.prune(max(int(opts.Age.OlderThan/(24*time.Hour)), 1))
+	}
+	if opts.DryRun() {
+		return nil
+	}
+	if _, err := run(ctx, bin, args...); err != nil {
+		return fmt.Errorf("failed to clean %s: %v", t.name, err)
+	}
+	return nil
+}
+
+// run runs a tool and returns its standard output. A failure carries what
+// the tool printed on standard error.
+func run(ctx context.Context, bin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%s %s: %s", filepath.Base(bin), strings.Join(args, " "), lastLine(msg))
+		}
+		return out, fmt.Errorf("%s %s: %w", filepath.Base(bin), strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+// lastLine returns the last line of msg, usually the one with the error
+func lastLine(msg string) string {
+	if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+		return strings.TrimSpace(msg[i+1:])
+	}
+	return msg
+}
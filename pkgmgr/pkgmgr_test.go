@@ -0,0 +1,308 @@
+package pkgmgr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/han-nwin/dusty/cleaner"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// fakeTools is a directory of shell scripts standing in for the package
+// managers, and the only thing on PATH
+type fakeTools struct {
+	bin  string
+	log  string // Each script appends its name and arguments here
+	home string
+}
+
+func newFakeTools(t *testing.T) *fakeTools {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	f := &fakeTools{
+		bin:  filepath.Join(dir, "bin"),
+		log:  filepath.Join(dir, "calls.log"),
+		home: filepath.Join(dir, "home"),
+	}
+	if err := os.MkdirAll(f.bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", f.bin)
+	return f
+}
+
+// add installs a fake tool that logs its arguments and then runs script
+func (f *fakeTools) add(t *testing.T, name, script string) {
+	t.Helper()
+	body := "#!/bin/sh\necho \"" + name + " $*\" >> '" + f.log + "'\n" + script + "\n"
+	if err := os.WriteFile(filepath.Join(f.bin, name), []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fakeTools) calls(t *testing.T) []string {
+	t.Helper()
+	data, err := os.ReadFile(f.log)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func (f *fakeTools) scanner() *scanner.Scanner {
+	return &scanner.Scanner{HomeDir: f.home, Workers: 2}
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scan runs a scan of f.home, which only the fake tools find anything in,
+// and returns the entries by name along with the errors
+func (f *fakeTools) scan(t *testing.T) (map[string]*scanner.CacheEntry, error) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	result, err := f.scanner().Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]*scanner.CacheEntry)
+	for _, e := range result.Entries {
+		got[e.Name] = e
+	}
+	return got, errors.Join(result.Errors...)
+}
+
+var (
+	npm  = findTool("npm")
+	brew = findTool("brew")
+	pip  = findTool("pip")
+)
+
+func TestScanParsesDryRuns(t *testing.T) {
+	f := newFakeTools(t)
+	f.add(t, "npm", `echo "~/.npm"`)
+	// The npm target's directory is left to npm
+	cacache := filepath.Join(f.home, ".npm", "_cacache")
+	writeFile(t, filepath.Join(cacache, "index-v5", "ab", "cd"), 500)
+	writeFile(t, filepath.Join(cacache, "content-v2", "sha512", "ef"), 1000)
+	writeFile(t, filepath.Join(f.home, ".npm", "_logs", "debug.log"), 10)
+
+	gocache := filepath.Join(filepath.Dir(f.home), "go-build")
+	writeFile(t, filepath.Join(gocache, "00", "a-d"), 100)
+	writeFile(t, filepath.Join(gocache, "01", "b-d"), 200)
+	writeFile(t, filepath.Join(gocache, "README"), 50)
+	f.add(t, "go", `
+case "$1" in
+env) echo "`+gocache+`" ;;
+clean) echo "rm -rf `+gocache+`/00 `+gocache+`/01" ;;
+esac`)
+
+	f.add(t, "brew", `
+case "$1" in
+--cache) echo "/opt/brew-cache" ;;
+cleanup)
+	echo "Would remove: /opt/brew-cache/wget--1.21.bottle.tar.gz (1.5MB)"
+	echo "Would remove: /opt/Cellar/git/2.40 (1,234 files, 40.2MB)"
+	echo "Would remove: /opt/brew-cache/empty.lock"
+	echo "==> This operation would free approximately 41.7MB of disk space." ;;
+esac`)
+
+	f.add(t, "pip3", `
+case "$2" in
+dir) echo "/opt/pip-cache" ;;
+info)
+	echo "Package index page cache location: /opt/pip-cache/http"
+	echo "Package index page cache size: 12.5 MB"
+	echo "Number of HTTP files: 30"
+	echo "Locally built wheels location: /opt/pip-cache/wheels"
+	echo "Locally built wheels size: 0 bytes"
+	echo "Number of locally built wheels: 0" ;;
+esac`)
+
+	got, err := f.scan(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 {
+		t.Errorf("entries = %v, want the 4 tools and no npm directory", got)
+	}
+
+	n := got["npm"]
+	if n == nil || n.Size != 1500 || n.FileCount != 2 {
+		t.Errorf("npm = %+v, want 1500 bytes in 2 files", n)
+	}
+	if n != nil && n.Covers != cacache {
+		t.Errorf("npm covers %q, want %q", n.Covers, cacache)
+	}
+	for _, call := range f.calls(t) {
+		if strings.HasPrefix(call, "npm ") && call != "npm config get cache" {
+			t.Errorf("scan ran %q, which may change the npm cache", call)
+		}
+	}
+
+	if g := got["go"]; g == nil || g.Size != 300 || g.Covers != gocache {
+		t.Errorf("go = %+v, want the 300 bytes go clean removes from %s", g, gocache)
+	}
+
+	b := got["brew"]
+	if b == nil || len(b.Children) != 2 {
+		t.Fatalf("brew = %+v, want the 2 sized items", b)
+	}
+	wget, git := 1.5, 40.2
+	if want := int64(wget*(1<<20)) + int64(git*(1<<20)); b.Size != want {
+		t.Errorf("brew size = %d, want %d", b.Size, want)
+	}
+	if item := b.Children[0]; item.Path != "tool:brew/wget--1.21.bottle.tar.gz" {
+		t.Errorf("brew item path = %q", item.Path)
+	}
+
+	p := got["pip"]
+	if p == nil || len(p.Children) != 1 || p.Size != 12500000 || p.Covers != "/opt/pip-cache" {
+		t.Errorf("pip = %+v, want only the 12.5 MB HTTP cache", p)
+	}
+}
+
+func TestScanSkipsMissingTools(t *testing.T) {
+	f := newFakeTools(t)
+	f.add(t, "npm", `echo "~/.npm"`)
+	writeFile(t, filepath.Join(f.home, ".npm", "_cacache", "index"), 10)
+
+	got, err := f.scan(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["npm"] == nil {
+		t.Errorf("entries = %v, want npm alone", got)
+	}
+}
+
+func TestScanReportsFailingTool(t *testing.T) {
+	f := newFakeTools(t)
+	f.add(t, "npm", `echo "~/.npm"`)
+	writeFile(t, filepath.Join(f.home, ".npm", "_cacache", "index"), 10)
+	f.add(t, "pip3", `echo "WARNING: something first" >&2; echo "ERROR: pip cache commands can not function since cache is disabled." >&2; exit 1`)
+	f.add(t, "brew", `echo "nothing to see"`)
+
+	got, err := f.scan(t)
+	if err == nil || !strings.Contains(err.Error(), "pip: ") || !strings.Contains(err.Error(), "cache is disabled") {
+		t.Errorf("err = %v, want pip's own message", err)
+	}
+	if got["npm"] == nil {
+		t.Error("npm left out because pip failed")
+	}
+	if got["brew"] != nil {
+		t.Errorf("brew = %+v, want it left out with nothing to clean", got["brew"])
+	}
+}
+
+func TestScanCountsToolCacheInsideTargetOnce(t *testing.T) {
+	f := newFakeTools(t)
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	gocache := filepath.Join(f.home, ".cache", "go-build")
+	writeFile(t, filepath.Join(gocache, "00", "a-d"), 100000)
+	writeFile(t, filepath.Join(f.home, ".cache", "other", "b"), 1000)
+	f.add(t, "go", `
+case "$1" in
+env) echo "`+gocache+`" ;;
+clean) echo "rm -rf `+gocache+`/00" ;;
+esac`)
+
+	result, err := f.scanner().Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, e := range result.Entries {
+		paths = append(paths, e.Path)
+	}
+	if want := []string{filepath.Join(f.home, ".cache")}; strings.Join(paths, "|") != strings.Join(want, "|") {
+		t.Errorf("entries = %q, want ~/.cache alone, counting the Go cache inside it", paths)
+	}
+	if result.TotalSize != 101000 {
+		t.Errorf("TotalSize = %d, want 101000", result.TotalSize)
+	}
+}
+
+func TestCleanRunsTool(t *testing.T) {
+	f := newFakeTools(t)
+	f.add(t, "npm", "")
+	f.add(t, "brew", "")
+	ctx := context.Background()
+	item := []*scanner.CacheEntry{{Path: "tool:npm/_cacache", AllocSize: 4096}}
+	month := scanner.CleanOptions{Age: scanner.AgeFilter{OlderThan: 30 * 24 * time.Hour}}
+
+	if r := npm.Clean(ctx, nil, item, scanner.CleanOptions{}); r.Err() != nil || r.Freed() != 4096 {
+		t.Fatalf("npm Clean = %d, %v; want 4096 freed", r.Freed(), r.Err())
+	}
+	if err := brew.Clean(ctx, nil, []*scanner.CacheEntry{{Path: "tool:brew"}}, month).Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"npm cache clean --force", "brew cleanup --prune=30"}
+	if got := f.calls(t); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+
+	// npm can't keep recent files, so it isn't run at all
+	if npm.Clean(ctx, nil, item, month).Err() == nil {
+		t.Error("cleaning npm by age succeeded")
+	}
+	// Nor is it run to move the cache to the trash, which it can't do
+	if err := npm.Clean(ctx, nil, item, scanner.CleanOptions{Strategy: cleaner.Trash}).Err(); !errors.Is(err, scanner.ErrNoTrash) {
+		t.Errorf("trashing npm: err = %v, want ErrNoTrash", err)
+	}
+	if pip.Clean(ctx, nil, []*scanner.CacheEntry{{Path: "tool:pip"}}, scanner.CleanOptions{}).Err() == nil {
+		t.Error("cleaning a tool that isn't installed succeeded")
+	}
+	if got := f.calls(t); len(got) != 2 {
+		t.Errorf("calls = %q, want no more", got)
+	}
+}
+
+func TestModuleFor(t *testing.T) {
+	if m := scanner.ModuleFor("tool:brew/wget.tar.gz"); m != scanner.Module(brew) {
+		t.Errorf("ModuleFor(brew item) = %s, want brew", m.Name())
+	}
+	if m := scanner.ModuleFor("tool:npmx"); m == scanner.Module(npm) {
+		t.Error("ModuleFor(tool:npmx) = npm")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		base float64
+		want int64
+	}{
+		{"340B", 1024, 340},
+		{"1.5MB", 1024, 1572864},
+		{"12.3 kB", 1000, 12300},
+		{"0 bytes", 1000, 0},
+		{"2 GB", 1000, 2000000000},
+	} {
+		got, err := parseSize(tc.in, tc.base)
+		if err != nil || got != tc.want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", tc.in, got, err, tc.want)
+		}
+	}
+	if _, err := parseSize("lots", 1000); err == nil {
+		t.Error("parseSize(lots) succeeded")
+	}
+}
@@ -0,0 +1,299 @@
+package pkgmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// tool is a package manager that sizes and cleans its own cache
+type tool struct {
+	name        string
+	bins        []string // Executables looked up on PATH, first found wins
+	description string
+	risk        string
+	clean       []string                // Arguments that clean the whole cache
+	prune       func(days int) []string // Arguments that clean files older than days, nil if unsupported
+
+	// scan runs the tool's dry run and returns where its cache lives and
+	// the items in it
+	scan func(ctx context.Context, s *scanner.Scanner, bin string) (dir string, items []*scanner.CacheEntry, err error)
+}
+
+var tools = []*tool{
+	{
+		name:        "npm",
+		bins:        []string{"npm"},
+		description: "npm cache (npm cache clean)",
+		risk:        "Packages are downloaded again on the next install",
+		clean:       []string{"cache", "clean", "--force"},
+		scan:        scanNpm,
+	},
+	{
+		name:        "go",
+		bins:        []string{"go"},
+		description: "Go build cache (go clean -cache)",
+		risk:        "The next builds and tests compile everything again",
+		clean:       []string{"clean", "-cache"},
+		scan:        scanGo,
+	},
+	{
+		name:        "brew",
+		bins:        []string{"brew"},
+		description: "Homebrew downloads and old versions (brew cleanup)",
+		risk:        "Old versions can't be switched back to without reinstalling",
+		clean:       []string{"cleanup"},
+		prune: func(days int) []string {
+			return []string{"cleanup", "--prune=" + strconv.Itoa(days)}
+		},
+		scan: scanBrew,
+	},
+	{
+		name:        "pip",
+		bins:        []string{"pip3", "pip"},
+		description: "pip cache (pip cache purge)",
+		risk:        "Packages are downloaded and wheels built again on the next install",
+		clean:       []string{"cache", "purge"},
+		scan:        scanPip,
+	},
+}
+
+// findTool returns the tool called name, or nil
+func findTool(name string) *tool {
+	for _, t := range tools {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// lookPath returns the tool's executable, or "" if it isn't installed
+func (t *tool) lookPath() string {
+	for _, bin := range t.bins {
+		if path, err := exec.LookPath(bin); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// scanNpm sizes the content store of npm's cache by walking it. `npm cache
+// verify` would report its size too, but it garbage-collects the cache as it
+// goes, and a scan mustn't change anything.
+func scanNpm(ctx context.Context, s *scanner.Scanner, bin string) (string, []*scanner.CacheEntry, error) {
+	out, err := run(ctx, bin, "config", "get", "cache")
+	if err != nil {
+		return "", nil, err
+	}
+	cache := strings.TrimSpace(string(out))
+	if cache == "" {
+		return "", nil, errors.New("npm has no cache directory")
+	}
+	dir := filepath.Join(expandHome(cache, s.HomeDir), "_cacache")
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return dir, nil, nil
+	}
+	item, err := sizeDir(ctx, s, dir, nil)
+	if err != nil {
+		return dir, nil, err
+	}
+	return dir, []*scanner.CacheEntry{item}, nil
+}
+
+// scanGo sizes what `go clean -n -cache` would remove from the build cache
+func scanGo(ctx context.Context, s *scanner.Scanner, bin string) (string, []*scanner.CacheEntry, error) {
+	env, err := run(ctx, bin, "env", "GOCACHE")
+	if err != nil {
+		return "", nil, err
+	}
+	dir := strings.TrimSpace(string(env))
+	if dir == "" || dir == "off" {
+		return "", nil, nil
+	}
+	out, err := run(ctx, bin, "clean", "-n", "-cache")
+	if err != nil {
+		return "", nil, err
+	}
+	doomed := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "rm" {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				doomed[filepath.Clean(f)] = true
+			}
+		}
+	}
+	if len(doomed) == 0 {
+		return dir, nil, nil
+	}
+
+	item, err := sizeDir(ctx, s, dir, func(path string) bool { return doomed[path] })
+	if err != nil {
+		return dir, nil, err
+	}
+	return dir, []*scanner.CacheEntry{item}, nil
+}
+
+// sizeDir adds up the items directly inside dir that keep accepts, or all
+// of them with a nil keep, into one item named after dir. Nothing is
+// changed on disk.
+func sizeDir(ctx context.Context, s *scanner.Scanner, dir string, keep func(path string) bool) (*scanner.CacheEntry, error) {
+	children, err := s.ScanChildren(ctx, &scanner.CacheEntry{Path: dir})
+	if err != nil {
+		return nil, err
+	}
+	item := &scanner.CacheEntry{Name: filepath.Base(dir)}
+	for _, c := range children {
+		if keep != nil && !keep(c.Path) {
+			continue
+		}
+		item.Size += c.Size
+		item.AllocSize += c.AllocSize
+		item.FileCount += c.FileCount
+		item.Ages.Merge(&c.Ages)
+		if c.LastMod.After(item.LastMod) {
+			item.LastMod = c.LastMod
+		}
+		if item.OldestMod.IsZero() || c.OldestMod.Before(item.OldestMod) {
+			item.OldestMod = c.OldestMod
+		}
+	}
+	return item, nil
+}
+
+// brewWouldRemove matches a line of `brew cleanup --dry-run`, such as
+// "Would remove: /Users/me/Library/Caches/Homebrew/wget--1.21.bottle.tar.gz (1.5MB)"
+var brewWouldRemove = regexp.MustCompile(`^Would remove: (.+?)(?: \((?:[\d,]+ files?, )?([\d.]+\s*[KMGT]?B)\))?$`)
+
+// scanBrew lists what `brew cleanup --dry-run` would remove: outdated
+// downloads and old versions of installed formulae
+func scanBrew(ctx context.Context, s *scanner.Scanner, bin string) (string, []*scanner.CacheEntry, error) {
+	var dir string
+	if out, err := run(ctx, bin, "--cache"); err == nil {
+		dir = strings.TrimSpace(string(out))
+	}
+	out, err := run(ctx, bin, "cleanup", "--dry-run")
+	if err != nil {
+		return "", nil, err
+	}
+	var items []*scanner.CacheEntry
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		m := brewWouldRemove.FindStringSubmatch(strings.TrimSpace(sc.Text()))
+		if m == nil || m[2] == "" {
+			continue
+		}
+		size, err := parseSize(m[2], 1024)
+		if err != nil {
+			continue
+		}
+		item := &scanner.CacheEntry{Name: filepath.Base(m[1]), Size: size, FileCount: 1}
+		stamp(item, m[1])
+		items = append(items, item)
+	}
+	return dir, items, sc.Err()
+}
+
+// scanPip sizes the HTTP cache and the locally built wheels from
+// `pip cache info`
+func scanPip(ctx context.Context, s *scanner.Scanner, bin string) (string, []*scanner.CacheEntry, error) {
+	var dir string
+	if out, err := run(ctx, bin, "cache", "dir"); err == nil {
+		dir = strings.TrimSpace(string(out))
+	}
+	out, err := run(ctx, bin, "cache", "info")
+	if err != nil {
+		return "", nil, err
+	}
+	http := &scanner.CacheEntry{Name: "http"}
+	wheels := &scanner.CacheEntry{Name: "wheels"}
+	locations := make(map[*scanner.CacheEntry]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		item := http
+		if strings.Contains(key, "wheels") {
+			item = wheels
+		}
+		switch {
+		case strings.HasSuffix(key, "size"):
+			item.Size, _ = parseSize(value, 1000)
+		case strings.HasPrefix(key, "number of"):
+			item.FileCount, _ = strconv.Atoi(value)
+		case strings.HasSuffix(key, "location"):
+			locations[item] = value
+		}
+	}
+	for item, path := range locations {
+		stamp(item, path)
+	}
+	return dir, []*scanner.CacheEntry{http, wheels}, nil
+}
+
+// parseSize reads a size like "1.5MB", "340 B", "12.3 kB" or "0 bytes",
+// with units that are powers of base
+func parseSize(s string, base float64) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	unit := strings.ToUpper(strings.TrimSpace(s[i:]))
+	scale := 1.0
+	switch unit {
+	case "", "B", "BYTE", "BYTES":
+	case "KB", "KIB":
+		scale = base
+	case "MB", "MIB":
+		scale = base * base
+	case "GB", "GIB":
+		scale = base * base * base
+	case "TB", "TIB":
+		scale = base * base * base * base
+	default:
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * scale), nil
+}
+
+// stamp dates item by the modification time of path, if it exists
+func stamp(item *scanner.CacheEntry, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	item.LastMod = info.ModTime()
+	item.OldestMod = info.ModTime()
+	item.Ages.Add(info.ModTime(), time.Now(), item.Size)
+}
+
+// expandHome expands a leading ~ in a path a tool printed
+func expandHome(path, home string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
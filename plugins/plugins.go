@@ -0,0 +1,397 @@
+// Package plugins runs external programs in dusty's plugins directory as
+// target modules, for caches dusty doesn't know about. Each program is
+// called once per request and speaks the JSON protocol in protocol.go.
+package plugins
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/han-nwin/dusty/cleaner"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// Root prefixes the path of every plugin entry: Root, the plugin's file
+// name, then a slash and an item ID for the items below it
+const Root = "plugin:"
+
+// maxOutput bounds what a plugin may print on either stream
+const maxOutput = 16 << 20
+
+// How long each call may take before the plugin is killed
+var (
+	describeTimeout = 5 * time.Second
+	scanTimeout     = 2 * time.Minute
+	cleanTimeout    = 10 * time.Minute
+)
+
+func init() {
+	scanner.Register(module{})
+}
+
+// Dir returns the directory plugins are found in
+func Dir(s *scanner.Scanner) string {
+	return filepath.Join(s.ConfigDir(), "plugins")
+}
+
+// plugin is an executable in Dir
+type plugin struct {
+	file string // Name in Dir, unique, and part of its entries' paths
+	path string
+}
+
+// find lists the executables in Dir, in name order. A missing directory
+// has none.
+func find(s *scanner.Scanner) []plugin {
+	dir := Dir(s)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var found []plugin
+	for _, de := range entries {
+		info, err := de.Info()
+		if err != nil || !info.Mode().IsRegular() || strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode()&0o111 == 0 {
+			continue // Not executable, like a README
+		}
+		found = append(found, plugin{file: de.Name(), path: filepath.Join(dir, de.Name())})
+	}
+	return found
+}
+
+// call sends req to the plugin and decodes its answer. Whatever the plugin
+// does wrong, be it crashing, hanging past timeout or printing something
+// else than the protocol, comes back as an error naming it.
+func (p plugin) call(ctx context.Context, req Request, timeout time.Duration) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req.Version = Version
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr limitedBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Don't wait on pipes a killed plugin left to its children
+	err = cmd.Run()
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("plugin %s: %s timed out after %v", p.file, req.Call, timeout)
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case stdout.overflow || stderr.overflow:
+		return nil, fmt.Errorf("plugin %s: %s printed more than %d MB", p.file, req.Call, maxOutput>>20)
+	}
+
+	var resp Response
+	decodeErr := json.Unmarshal(stdout.Bytes(), &resp)
+	if err != nil {
+		// A plugin may explain its failure in the protocol or on stderr
+		if decodeErr == nil && resp.Error != "" {
+			return nil, fmt.Errorf("plugin %s: %s", p.file, resp.Error)
+		}
+		if msg := lastLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s: %s failed: %s", p.file, req.Call, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %s failed: %v", p.file, req.Call, err)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response to %s: %v", p.file, req.Call, decodeErr)
+	}
+	if resp.Version != Version {
+		return nil, fmt.Errorf("plugin %s: speaks protocol version %d, dusty speaks %d", p.file, resp.Version, Version)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", p.file, resp.Error)
+	}
+	return &resp, nil
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxOutput {
+		b.overflow = true
+		return 0, io.ErrShortWrite
+	}
+	return b.Buffer.Write(p)
+}
+
+// lastLine returns the last non-empty line of msg, usually the one with the
+// error
+func lastLine(msg string) string {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// scan describes the plugin and lists its items as an entry tree
+func (p plugin) scan(ctx context.Context) (*scanner.CacheEntry, error) {
+	desc, err := p.call(ctx, Request{Call: CallDescribe}, describeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.call(ctx, Request{Call: CallScan}, scanTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &scanner.CacheEntry{
+		Name:        cmp.Or(desc.Name, p.file),
+		Path:        Root + p.file,
+		Description: cmp.Or(desc.Description, desc.Name, p.file),
+		Category:    desc.Category,
+		Risk:        desc.Risk,
+		IsParent:    true,
+		IsDir:       true,
+		Listed:      true,
+	}
+	seen := make(map[string]bool)
+	now := time.Now()
+	for _, item := range resp.Items {
+		child, err := p.entry(item, root, seen, now)
+		if err != nil {
+			return nil, err
+		}
+		addTotals(root, child)
+		root.Children = append(root.Children, child)
+	}
+	return root, nil
+}
+
+// entry turns an item and the ones inside it into entries below parent
+func (p plugin) entry(item Item, parent *scanner.CacheEntry, seen map[string]bool, now time.Time) (*scanner.CacheEntry, error) {
+	switch {
+	case item.ID == "":
+		return nil, fmt.Errorf("plugin %s: item %q has no id", p.file, item.Name)
+	case seen[item.ID]:
+		return nil, fmt.Errorf("plugin %s: duplicate item id %q", p.file, item.ID)
+	case item.Size < 0 || item.Files < 0:
+		return nil, fmt.Errorf("plugin %s: item %q has a negative size", p.file, item.ID)
+	}
+	seen[item.ID] = true
+
+	e := &scanner.CacheEntry{
+		Name:      cmp.Or(item.Name, item.ID),
+		Path:      Root + p.file + "/" + item.ID,
+		Risk:      parent.Risk,
+		Depth:     parent.Depth + 1,
+		LastMod:   item.Modified,
+		OldestMod: item.Modified,
+	}
+	if len(item.Children) == 0 {
+		e.Size = item.Size
+		e.AllocSize = item.Size
+		e.FileCount = max(item.Files, 1)
+		e.Ages.Add(item.Modified, now, item.Size)
+		return e, nil
+	}
+	e.IsDir, e.Listed = true, true
+	e.OldestMod = time.Time{}
+	for _, c := range item.Children {
+		child, err := p.entry(c, e, seen, now)
+		if err != nil {
+			return nil, err
+		}
+		addTotals(e, child)
+		e.Children = append(e.Children, child)
+	}
+	return e, nil
+}
+
+// addTotals adds child's sizes and ages to e
+func addTotals(e, child *scanner.CacheEntry) {
+	e.Size += child.Size
+	e.AllocSize += child.AllocSize
+	e.FileCount += child.FileCount
+	e.Ages.Merge(&child.Ages)
+	if child.LastMod.After(e.LastMod) {
+		e.LastMod = child.LastMod
+	}
+	if !child.OldestMod.IsZero() && (e.OldestMod.IsZero() || child.OldestMod.Before(e.OldestMod)) {
+		e.OldestMod = child.OldestMod
+	}
+}
+
+// module runs every plugin as part of one scanner module. A plugin that
+// fails is reported on its own; the others still show.
+type module struct{}
+
+func (module) Name() string { return "plugins" }
+
+func (module) Available(_ context.Context, s *scanner.Scanner) bool {
+	return len(find(s)) > 0
+}
+
+func (module) Owns(path string) bool { return strings.HasPrefix(path, Root) }
+
+// Scan runs the plugins in parallel
+func (module) Scan(ctx context.Context, s *scanner.Scanner) (*scanner.ScanResult, error) {
+	plugins := find(s)
+	entries := make([]*scanner.CacheEntry, len(plugins))
+	errs := make([]error, len(plugins))
+	var wg sync.WaitGroup
+	for i, p := range plugins {
+		wg.Go(func() {
+			entries[i], errs[i] = p.scan(ctx)
+		})
+	}
+	wg.Wait()
+
+	result := &scanner.ScanResult{}
+	for i, e := range entries {
+		if errs[i] != nil {
+			result.Errors = append(result.Errors, errs[i])
+			continue
+		}
+		result.Entries = append(result.Entries, e)
+	}
+	return result, ctx.Err()
+}
+
+// Clean asks each plugin to remove its selected items. A selected plugin
+// entry stands for all of its items. A dry run only adds up what the
+// plugins reported, and cleaner.Trash is refused: plugins delete for good.
+func (module) Clean(ctx context.Context, s *scanner.Scanner, entries []*scanner.CacheEntry, opts scanner.CleanOptions) *cleaner.Report {
+	if opts.Strategy == cleaner.Trash {
+		return scanner.RefuseTrash(entries)
+	}
+	start := time.Now()
+	report := &cleaner.Report{}
+	if opts.DryRun() {
+		for _, e := range entries {
+			freed := e.AllocSize
+			if opts.Age.Active() {
+				var match scanner.AgeMatch
+				countOlder(&match, e, time.Now().Add(-opts.Age.OlderThan))
+				freed = match.AllocSize
+			}
+			report.Add(cleaner.Result{Path: e.Path, Freed: freed})
+		}
+		report.Duration = time.Since(start)
+		return report
+	}
+
+	byPlugin := make(map[string][]string)
+	for _, e := range entries {
+		file, id, ok := strings.Cut(strings.TrimPrefix(e.Path, Root), "/")
+		if ok {
+			byPlugin[file] = append(byPlugin[file], id)
+			continue
+		}
+		for _, child := range e.Children {
+			_, id, _ := strings.Cut(strings.TrimPrefix(child.Path, Root), "/")
+			byPlugin[file] = append(byPlugin[file], id)
+		}
+	}
+
+	days := 0
+	if opts.Age.Active() {
+		days = max(int(opts.Age.OlderThan/(24*time.Hour)), 1)
+	}
+	for _, p := range find(s) {
+		ids := byPlugin[p.file]
+		if len(ids) == 0 {
+			continue
+		}
+		delete(byPlugin, p.file)
+		report.Merge(p.clean(ctx, ids, days))
+	}
+	for file := range byPlugin {
+		report.Add(cleaner.Result{Path: Root + file, Err: fmt.Errorf("plugin %s is gone from %s", file, Dir(s))})
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (module) MatchOlderThan(_ context.Context, _ *scanner.Scanner, entries []*scanner.CacheEntry, f scanner.AgeFilter) (scanner.AgeMatch, error) {
+	var total scanner.AgeMatch
+	if !f.Active() {
+		return total, nil
+	}
+	cutoff := time.Now().Add(-f.OlderThan)
+	for _, e := range entries {
+		countOlder(&total, e, cutoff)
+	}
+	return total, nil
+}
+
+// countOlder adds the items at or below e modified before cutoff, as the
+// plugin reported them, to total
+func countOlder(total *scanner.AgeMatch, e *scanner.CacheEntry, cutoff time.Time) {
+	if len(e.Children) > 0 {
+		for _, child := range e.Children {
+			countOlder(total, child, cutoff)
+		}
+		return
+	}
+	if e.LastMod.Before(cutoff) {
+		total.Files += e.FileCount
+		total.Size += e.Size
+		total.AllocSize += e.AllocSize
+	}
+}
+
+// clean asks the plugin to remove the items with the given IDs. The report
+// has the plugin's total, then a failure for each item it couldn't remove.
+func (p plugin) clean(ctx context.Context, ids []string, days int) *cleaner.Report {
+	start := time.Now()
+	report := &cleaner.Report{}
+	res := cleaner.Result{Path: Root + p.file}
+	defer func() {
+		res.Duration = time.Since(start)
+		report.Results = slices.Insert(report.Results, 0, res)
+		report.Duration = res.Duration
+	}()
+
+	if days > 0 {
+		desc, err := p.call(ctx, Request{Call: CallDescribe}, describeTimeout)
+		if err != nil {
+			res.Err = err
+			return report
+		}
+		if !desc.CleanByAge {
+			res.Err = fmt.Errorf("plugin %s can't clean by age; turn off the age cutoff to clean it", p.file)
+			return report
+		}
+	}
+	resp, err := p.call(ctx, Request{Call: CallClean, IDs: ids, OlderThanDays: days}, cleanTimeout)
+	if err != nil {
+		res.Err = err
+		return report
+	}
+	res.Freed = max(resp.Freed, 0)
+	for _, f := range resp.Failed {
+		if !slices.Contains(ids, f.ID) {
+			continue // Not something dusty asked for
+		}
+		report.Add(cleaner.Result{
+			Path: Root + p.file + "/" + f.ID,
+			Err:  fmt.Errorf("plugin %s: failed to clean %s: %s", p.file, f.ID, f.Error),
+		})
+	}
+	return report
+}
@@ -0,0 +1,198 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// fixture is a scanner whose plugins directory holds shell scripts
+type fixture struct {
+	s   *scanner.Scanner
+	dir string
+	log string // The mirror plugin appends each request here
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake plugins are shell scripts")
+	}
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	f := &fixture{
+		s:   &scanner.Scanner{HomeDir: filepath.Join(root, "home"), Workers: 2},
+		log: filepath.Join(root, "requests.log"),
+	}
+	f.dir = Dir(f.s)
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f *fixture) add(t *testing.T, name, script string, mode os.FileMode) {
+	t.Helper()
+	body := "#!/bin/sh\nreq=$(cat)\n" + script + "\n"
+	if err := os.WriteFile(filepath.Join(f.dir, name), []byte(body), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addMirror installs a well-behaved plugin
+func (f *fixture) addMirror(t *testing.T) {
+	f.add(t, "mirror", `echo "$req" >> '`+f.log+`'
+case "$req" in
+*'"call":"describe"'*) echo '{"version":1,"name":"Mirror","description":"Artifact mirror","category":"Developer","risk":"Artifacts are fetched again","clean_by_age":true}' ;;
+*'"call":"scan"'*) echo '{"version":1,"items":[
+	{"id":"maven","name":"Maven","children":[
+		{"id":"maven/a.jar","name":"a.jar","size":1000,"modified":"2020-01-01T00:00:00Z"},
+		{"id":"maven/b.jar","size":500,"modified":"2099-01-01T00:00:00Z"}]},
+	{"id":"pypi","name":"PyPI","size":2000,"files":3,"modified":"2020-01-01T00:00:00Z"}]}' ;;
+*'"call":"clean"'*) echo '{"version":1,"freed":1500,"failed":[{"id":"pypi","error":"locked by another process"}]}' ;;
+esac`, 0o755)
+}
+
+func (f *fixture) scan(t *testing.T) (*scanner.ScanResult, map[string]*scanner.CacheEntry) {
+	t.Helper()
+	result, err := f.s.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := make(map[string]*scanner.CacheEntry)
+	var walk func([]*scanner.CacheEntry)
+	walk = func(entries []*scanner.CacheEntry) {
+		for _, e := range entries {
+			byPath[e.Path] = e
+			walk(e.Children)
+		}
+	}
+	walk(result.Entries)
+	return result, byPath
+}
+
+func TestScanShowsPluginItems(t *testing.T) {
+	f := newFixture(t)
+	f.addMirror(t)
+	f.add(t, "README", "not a plugin", 0o644)
+
+	result, got := f.scan(t)
+	if len(result.Errors) > 0 {
+		t.Fatal(errors.Join(result.Errors...))
+	}
+	if len(result.Entries) != 1 {
+		t.Fatalf("entries = %d, want the mirror alone", len(result.Entries))
+	}
+	root := result.Entries[0]
+	if root.Path != "plugin:mirror" || root.Name != "Mirror" || root.Category != scanner.CategoryDeveloper || root.Size != 3500 {
+		t.Errorf("root = %+v, want Mirror with 3500 bytes", root)
+	}
+	if maven := got["plugin:mirror/maven"]; maven == nil || maven.Size != 1500 || len(maven.Children) != 2 {
+		t.Errorf("maven = %+v, want its 2 jars' 1500 bytes", maven)
+	}
+	if b := got["plugin:mirror/maven/b.jar"]; b == nil || b.Name != "maven/b.jar" {
+		t.Errorf("b.jar = %+v, want it named by its id", b)
+	}
+	if pypi := got["plugin:mirror/pypi"]; pypi == nil || pypi.FileCount != 3 {
+		t.Errorf("pypi = %+v, want 3 files", pypi)
+	}
+	if r := got["plugin:mirror/pypi"].Risk; r != "Artifacts are fetched again" {
+		t.Errorf("pypi risk = %q, want the plugin's", r)
+	}
+}
+
+func TestMisbehavingPluginsAreIsolated(t *testing.T) {
+	describeTimeout = 300 * time.Millisecond
+	defer func() { describeTimeout = 5 * time.Second }()
+
+	f := newFixture(t)
+	f.addMirror(t)
+	f.add(t, "chatty", `echo "hello there"`, 0o755)
+	f.add(t, "future", `echo '{"version":2,"name":"Future"}'`, 0o755)
+	f.add(t, "crash", `echo "Traceback: something broke" >&2; exit 3`, 0o755)
+	f.add(t, "hang", `exec sleep 10`, 0o755)
+	f.add(t, "refuse", `echo '{"version":1,"error":"mirror server unreachable"}'; exit 1`, 0o755)
+	f.add(t, "dupes", `case "$req" in
+*describe*) echo '{"version":1}' ;;
+*) echo '{"version":1,"items":[{"id":"x","size":1},{"id":"x","size":2}]}' ;;
+esac`, 0o755)
+
+	start := time.Now()
+	result, got := f.scan(t)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("scan took %v, want the hung plugin cut off", elapsed)
+	}
+	if got["plugin:mirror"] == nil {
+		t.Error("the mirror is missing because of the others")
+	}
+
+	msg := errors.Join(result.Errors...).Error()
+	for _, want := range []string{
+		"plugin chatty: invalid response to describe",
+		"plugin future: speaks protocol version 2, dusty speaks 1",
+		"plugin crash: describe failed: Traceback: something broke",
+		"plugin hang: describe timed out",
+		"plugin refuse: mirror server unreachable",
+		`plugin dupes: duplicate item id "x"`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("errors = %s\nwant %q", msg, want)
+		}
+	}
+	if len(result.Entries) != 1 {
+		t.Errorf("entries = %d, want the mirror alone", len(result.Entries))
+	}
+}
+
+func TestCleanSendsItemIDs(t *testing.T) {
+	f := newFixture(t)
+	f.addMirror(t)
+	_, got := f.scan(t)
+	os.Remove(f.log)
+
+	selected := []*scanner.CacheEntry{got["plugin:mirror/maven"], got["plugin:mirror/pypi"]}
+	report := module{}.Clean(context.Background(), f.s, selected, scanner.CleanOptions{})
+	if freed := report.Freed(); freed != 1500 {
+		t.Errorf("freed = %d, want the 1500 the plugin reported", freed)
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Path != "plugin:mirror/pypi" {
+		t.Errorf("failed = %+v, want just pypi", failed)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "plugin mirror: failed to clean pypi: locked by another process") {
+		t.Errorf("err = %v, want the item the plugin couldn't clean", err)
+	}
+	data, _ := os.ReadFile(f.log)
+	if want := `{"version":1,"call":"clean","ids":["maven","pypi"]}`; strings.TrimSpace(string(data)) != want {
+		t.Errorf("request = %s, want %s", data, want)
+	}
+}
+
+func TestCleanByAge(t *testing.T) {
+	f := newFixture(t)
+	f.addMirror(t)
+	_, got := f.scan(t)
+	root := got["plugin:mirror"]
+	month := scanner.AgeFilter{OlderThan: 30 * 24 * time.Hour}
+
+	match, err := module{}.MatchOlderThan(context.Background(), f.s, []*scanner.CacheEntry{root}, month)
+	if err != nil || match.Size != 3000 || match.Files != 4 {
+		t.Errorf("match = %+v, %v; want a.jar and pypi", match, err)
+	}
+
+	os.Remove(f.log)
+	module{}.Clean(context.Background(), f.s, []*scanner.CacheEntry{root}, scanner.CleanOptions{Age: month})
+	data, _ := os.ReadFile(f.log)
+	if !strings.Contains(string(data), `{"version":1,"call":"clean","ids":["maven","pypi"],"older_than_days":30}`) {
+		t.Errorf("requests = %s, want a clean of every item older than 30 days", data)
+	}
+}
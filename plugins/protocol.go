@@ -0,0 +1,60 @@
+package plugins
+
+import "time"
+
+// Version is the protocol version dusty speaks. Every request carries it,
+// and a plugin must answer with the same version.
+const Version = 1
+
+// Calls a plugin answers
+const (
+	CallDescribe = "describe"
+	CallScan     = "scan"
+	CallClean    = "clean"
+)
+
+// Request is what dusty writes to a plugin's standard input, as a single
+// JSON object, before closing it
+type Request struct {
+	Version       int      `json:"version"`
+	Call          string   `json:"call"`
+	IDs           []string `json:"ids,omitempty"`             // clean: the items to remove, each with everything below it
+	OlderThanDays int      `json:"older_than_days,omitempty"` // clean: only remove what is older, if the plugin supports it
+}
+
+// Response is what a plugin writes to its standard output, as a single JSON
+// object. Error is set, and nothing else needs to be, if the call failed.
+type Response struct {
+	Version int    `json:"version"`
+	Error   string `json:"error,omitempty"`
+
+	// describe
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Risk        string `json:"risk,omitempty"`
+	CleanByAge  bool   `json:"clean_by_age,omitempty"`
+
+	// scan
+	Items []Item `json:"items,omitempty"`
+
+	// clean
+	Freed  int64       `json:"freed,omitempty"`
+	Failed []ItemError `json:"failed,omitempty"`
+}
+
+// Item is something a plugin can clean, with the items inside it
+type Item struct {
+	ID       string    `json:"id"` // Unique within the plugin; passed back to clean
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"` // Bytes; for an item with children, their sum is used instead
+	Files    int       `json:"files,omitempty"`
+	Modified time.Time `json:"modified,omitzero"`
+	Children []Item    `json:"children,omitempty"`
+}
+
+// ItemError reports an item a plugin couldn't clean
+type ItemError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
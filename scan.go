@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// runScan is the scan subcommand: it sizes the targets without the TUI and
+// prints them largest first. Cancelling ctx stops the walk; what was sized
+// by then is still printed. It returns the exit status.
+func runScan(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dusty scan [-x] [-profile NAME]")
+		fs.PrintDefaults()
+	}
+	oneFS := fs.Bool("x", false, "stay on the filesystem of each target")
+	profile := fs.String("profile", "", "profile to scan: Full, Developer, Browser or one from the config file")
+	var projects []string
+	fs.Func("projects", "search `dir` for build artifacts (repeatable)", func(dir string) error {
+		projects = append(projects, dir)
+		return nil
+	})
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	s, err := scanner.NewScanner()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	s.OneFileSystem = *oneFS
+	s.Profile = *profile
+	s.Projects = projects
+
+	result, err := s.Scan(ctx)
+	if result == nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	for _, e := range result.Entries {
+		fmt.Printf("%10s  %-40s %s\n", scanner.FormatSize(e.AllocSize), scanner.ShortenPath(e.Path), e.Description)
+	}
+	for _, scanErr := range result.Errors {
+		fmt.Printf("Error: %v\n", scanErr)
+	}
+	fmt.Printf("Total %s on disk (%s apparent) in %s\n", scanner.FormatSize(result.TotalAllocSize),
+		scanner.FormatSize(result.TotalSize), result.ScanTime.Round(time.Millisecond))
+
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Scan interrupted; sizes are partial")
+		return 130
+	}
+	if err != nil || len(result.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,192 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AgeBasis picks which timestamp an AgeFilter compares
+type AgeBasis int
+
+const (
+	ByModTime    AgeBasis = iota // Last modified (mtime)
+	ByAccessTime                 // Last read (atime)
+)
+
+func (b AgeBasis) String() string {
+	if b == ByAccessTime {
+		return "atime"
+	}
+	return "mtime"
+}
+
+// AgeFilter selects files older than a cutoff, for cleaning inside an entry
+// instead of removing it whole. A zero OlderThan selects nothing.
+type AgeFilter struct {
+	OlderThan time.Duration
+	Basis     AgeBasis
+}
+
+// Active reports whether the filter is switched on
+func (f AgeFilter) Active() bool {
+	return f.OlderThan > 0
+}
+
+func (f AgeFilter) matches(info os.FileInfo, cutoff time.Time) bool {
+	t := info.ModTime()
+	if f.Basis == ByAccessTime {
+		t = accessTime(info)
+	}
+	return t.Before(cutoff)
+}
+
+// AgeMatch counts the files an AgeFilter selected
+type AgeMatch struct {
+	Files     int
+	Size      int64
+	AllocSize int64
+}
+
+func (a *AgeMatch) add(info os.FileInfo) {
+	a.Files++
+	a.Size += info.Size()
+	a.AllocSize += allocatedSize(info)
+}
+
+// MatchOlderThan counts the files under path that RemoveOlderThan would
+// remove
+func (s *Scanner) MatchOlderThan(path string, f AgeFilter) (AgeMatch, error) {
+	return s.walkOlderThan(path, f, false)
+}
+
+// RemoveOlderThan removes the files under path that f selects, then prunes
+// subdirectories left empty. path itself is kept even if empty. Excluded
+// items, symlink targets and, with OneFileSystem, other filesystems are left
+// alone. It keeps going past files it can't remove and returns the first
+// such error along with what was removed.
+func (s *Scanner) RemoveOlderThan(path string, f AgeFilter) (AgeMatch, error) {
+	return s.walkOlderThan(path, f, true)
+}
+
+func (s *Scanner) walkOlderThan(path string, f AgeFilter, remove bool) (AgeMatch, error) {
+	var matched AgeMatch
+	if !f.Active() {
+		return matched, nil
+	}
+	cutoff := time.Now().Add(-f.OlderThan)
+	ex := s.exclusions()
+
+	var firstErr error
+	var dirs []string
+	var rootDev fileID
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return nil
+		}
+		if p == path {
+			rootDev, _, _ = fileIdentity(info)
+		} else {
+			if _, ok := ex.match(p); ok {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if s.OneFileSystem && info.IsDir() && !sameDevice(info, rootDev) {
+				return filepath.SkipDir
+			}
+		}
+
+		if info.IsDir() {
+			if p != path {
+				dirs = append(dirs, p)
+			}
+			return nil
+		}
+		if !f.matches(info, cutoff) {
+			return nil
+		}
+		if remove {
+			if err := os.Remove(p); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return nil
+			}
+		}
+		matched.add(info)
+		return nil
+	})
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	if remove {
+		// Walk order is parents first, so going backwards empties children
+		// before their parents; non-empty directories just fail to go
+		for i := len(dirs) - 1; i >= 0; i-- {
+			os.Remove(dirs[i])
+		}
+	}
+	return matched, firstErr
+}
+
+// Buckets of an AgeHistogram, by time since last modification
+const (
+	AgeDay     = iota // Under a day
+	AgeWeek           // Under 7 days
+	AgeMonth          // Under 30 days
+	AgeQuarter        // Under 90 days
+	AgeOlder          // 90 days or more
+	NumAgeBuckets
+)
+
+// AgeBucketLabels names the buckets of an AgeHistogram
+var AgeBucketLabels = [NumAgeBuckets]string{"<1d", "<7d", "<30d", "<90d", "older"}
+
+// ageBucketLimits are the upper bounds of every bucket but the last
+var ageBucketLimits = [NumAgeBuckets - 1]time.Duration{
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	90 * 24 * time.Hour,
+}
+
+// AgeBucket counts the files of one age bucket
+type AgeBucket struct {
+	Files int
+	Size  int64 // Apparent size
+}
+
+// AgeHistogram splits an entry's bytes by modification age, to tell a cache
+// that's stale as a whole from one that only needs trimming
+type AgeHistogram [NumAgeBuckets]AgeBucket
+
+// Add counts a file, or anything else of the given size, modified at mod as
+// seen at now
+func (h *AgeHistogram) Add(mod, now time.Time, size int64) {
+	h.addFiles(mod, now, AgeBucket{Files: 1, Size: size})
+}
+
+// addFiles counts files modified at mod, as seen at now
+func (h *AgeHistogram) addFiles(mod, now time.Time, files AgeBucket) {
+	age := now.Sub(mod)
+	i := 0
+	for i < len(ageBucketLimits) && age >= ageBucketLimits[i] {
+		i++
+	}
+	h[i].Files += files.Files
+	h[i].Size += files.Size
+}
+
+// Merge adds o's counts to h
+func (h *AgeHistogram) Merge(o *AgeHistogram) {
+	for i := range h {
+		h[i].Files += o[i].Files
+		h[i].Size += o[i].Size
+	}
+}
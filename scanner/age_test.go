@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// age sets path's access and modification times to the given ages
+func age(t *testing.T, path string, atime, mtime time.Duration) {
+	t.Helper()
+	now := time.Now()
+	if err := os.Chtimes(path, now.Add(-atime), now.Add(-mtime)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const day = 24 * time.Hour
+
+func TestRemoveOlderThan(t *testing.T) {
+	f := newFixture(t)
+	files := map[string]time.Duration{
+		"fresh":         time.Hour,
+		"stale":         60 * day,
+		"mixed/fresh":   time.Hour,
+		"mixed/stale":   60 * day,
+		"old/a":         90 * day,
+		"old/deep/b":    90 * day,
+		"keep/stale":    60 * day, // Excluded
+		"keep/in/stale": 60 * day,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(f.root, name)
+		writeFile(t, path, 100)
+		age(t, path, mtime, mtime)
+	}
+	s := f.scanner()
+	s.Config = &Config{Exclude: []string{filepath.Join(f.root, "keep")}}
+	month := AgeFilter{OlderThan: 30 * day}
+
+	match, err := s.MatchOlderThan(f.root, month)
+	if err != nil || match.Files != 4 || match.Size != 400 {
+		t.Errorf("MatchOlderThan = %+v, %v; want 4 files, 400 bytes", match, err)
+	}
+	if !exists(filepath.Join(f.root, "stale")) {
+		t.Fatal("MatchOlderThan removed files")
+	}
+
+	removed, err := s.RemoveOlderThan(f.root, month)
+	if err != nil || removed != match {
+		t.Errorf("RemoveOlderThan = %+v, %v; want what MatchOlderThan found, %+v", removed, err, match)
+	}
+	for _, name := range []string{"stale", "mixed/stale", "old"} {
+		if exists(filepath.Join(f.root, name)) {
+			t.Errorf("%s is still there", name)
+		}
+	}
+	for _, name := range []string{"fresh", "mixed/fresh", "keep/stale", "keep/in/stale"} {
+		if !exists(filepath.Join(f.root, name)) {
+			t.Errorf("%s was removed", name)
+		}
+	}
+}
+
+func TestRemoveOlderThanKeepsRoot(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "dir", "stale"), 100)
+	age(t, filepath.Join(f.root, "dir", "stale"), 60*day, 60*day)
+
+	if _, err := f.scanner().RemoveOlderThan(f.root, AgeFilter{OlderThan: 30 * day}); err != nil {
+		t.Fatal(err)
+	}
+	if exists(filepath.Join(f.root, "dir")) {
+		t.Error("empty dir wasn't pruned")
+	}
+	if !exists(f.root) {
+		t.Error("the root was removed")
+	}
+}
+
+func TestRemoveOlderThanByAccessTime(t *testing.T) {
+	f := newFixture(t)
+	unread := filepath.Join(f.root, "unread") // Written lately, not read for long
+	unwritten := filepath.Join(f.root, "unwritten")
+	writeFile(t, unread, 100)
+	writeFile(t, unwritten, 100)
+	age(t, unread, 60*day, time.Hour)
+	age(t, unwritten, time.Hour, 60*day)
+
+	s := f.scanner()
+	match, err := s.MatchOlderThan(f.root, AgeFilter{OlderThan: 30 * day})
+	if err != nil || match.Files != 1 {
+		t.Errorf("by mtime: %+v, %v; want 1 file", match, err)
+	}
+	if _, err := s.RemoveOlderThan(f.root, AgeFilter{OlderThan: 30 * day, Basis: ByAccessTime}); err != nil {
+		t.Fatal(err)
+	}
+	if exists(unread) || !exists(unwritten) {
+		t.Errorf("unread, unwritten exist = %v, %v; want only unwritten left", exists(unread), exists(unwritten))
+	}
+}
+
+func TestInactiveAgeFilterRemovesNothing(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "stale"), 100)
+	age(t, filepath.Join(f.root, "stale"), 60*day, 60*day)
+
+	removed, err := f.scanner().RemoveOlderThan(f.root, AgeFilter{})
+	if err != nil || removed.Files != 0 || !exists(filepath.Join(f.root, "stale")) {
+		t.Errorf("RemoveOlderThan = %+v, %v; want nothing removed", removed, err)
+	}
+}
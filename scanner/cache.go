@@ -0,0 +1,249 @@
+package scanner
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheFile is the name of the scan cache inside CacheDir
+const CacheFile = "scan.cache"
+
+// cacheVersion changes whenever dirRecord does, discarding older caches
+const cacheVersion = 2
+
+// dirRecord is what the scan cache remembers about one directory: its own
+// files, summed up, and the names of its subdirectories. It's valid as long
+// as the directory keeps its inode and mtime, which change whenever an entry
+// is added, removed or renamed in it. Files rewritten in place don't touch
+// the mtime, so their new sizes show up only after ClearCache.
+type dirRecord struct {
+	Dev, Ino uint64
+	ModTime  int64    // Directory mtime, in nanoseconds
+	Subdirs  []string // Real subdirectories, not symlinks to them
+
+	Files    int                 // Every counted file, hard-linked ones included
+	Size     int64               // Files without other hard links
+	Alloc    int64               // On-disk size of the same files
+	Hours    map[int64]AgeBucket // The same files by modification hour, for AgeHistogram
+	Newest   int64               // Latest mtime of every counted file, in nanoseconds
+	Oldest   int64               // Earliest mtime of every counted file, in nanoseconds
+	Excluded int64               // Bytes of files matching an exclude rule
+	Links    []cachedLink        // Files with other hard links, charged by linkTracker
+	Large    []cachedFile        // Up to LargestFiles of the largest files without other hard links
+}
+
+// cachedLink is a hard-linked file inside a cached directory
+type cachedLink struct {
+	Dev, Ino, Nlink uint64
+	Size, Alloc     int64
+	ModTime         int64 // In nanoseconds
+}
+
+// cachedFile is one of the largest files of a cached directory
+type cachedFile struct {
+	Name        string
+	Size, Alloc int64
+	ModTime     int64 // In nanoseconds
+}
+
+func newCachedFile(info os.FileInfo) cachedFile {
+	return cachedFile{Name: info.Name(), Size: info.Size(), Alloc: allocatedSize(info), ModTime: info.ModTime().UnixNano()}
+}
+
+// cacheFile is the on-disk form of a ScanCache
+type cacheFile struct {
+	Version int
+	Key     string // Settings the records depend on; see cacheKey
+	Dirs    map[string]*dirRecord
+}
+
+// ScanCache keeps directory records between scans so unchanged directories
+// aren't read again: a rescan only has to stat each directory. It's safe for
+// concurrent use.
+type ScanCache struct {
+	path string
+	key  string
+
+	mu   sync.Mutex
+	old  map[string]*dirRecord // Loaded from disk
+	seen map[string]*dirRecord // Used or rebuilt since the last save
+}
+
+// CacheDir returns dusty's cache directory, $XDG_CACHE_HOME/dusty
+// (~/.cache/dusty by default) on every platform
+func (s *Scanner) CacheDir() string {
+	return filepath.Join(s.xdgDir("XDG_CACHE_HOME", ".cache"), "dusty")
+}
+
+// LoadCache reads the scan cache. A cache that is missing, unreadable or
+// written with different exclude rules is discarded, so this never fails.
+func (s *Scanner) LoadCache() *ScanCache {
+	c := &ScanCache{
+		path: filepath.Join(s.CacheDir(), CacheFile),
+		key:  s.cacheKey(),
+		old:  make(map[string]*dirRecord),
+		seen: make(map[string]*dirRecord),
+	}
+	f, err := os.Open(c.path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+	var cf cacheFile
+	if gob.NewDecoder(f).Decode(&cf) == nil && cf.Version == cacheVersion && cf.Key == c.key && cf.Dirs != nil {
+		c.old = cf.Dirs
+	}
+	return c
+}
+
+// ClearCache deletes the scan cache, so the next scan reads everything
+func (s *Scanner) ClearCache() error {
+	if s.Cache != nil {
+		s.Cache.mu.Lock()
+		s.Cache.old = make(map[string]*dirRecord)
+		s.Cache.seen = make(map[string]*dirRecord)
+		s.Cache.mu.Unlock()
+	}
+	err := os.Remove(filepath.Join(s.CacheDir(), CacheFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// cacheKey describes the settings a dirRecord depends on. Exclude rules
+// decide which files count as excluded; anything else is checked on use.
+func (s *Scanner) cacheKey() string {
+	if s.Config == nil {
+		return ""
+	}
+	var rules []string
+	for _, p := range s.Config.Exclude {
+		rules = append(rules, "*:"+p)
+	}
+	for _, t := range s.Config.Targets {
+		for _, p := range t.Exclude {
+			rules = append(rules, t.Path+":"+p)
+		}
+	}
+	sort.Strings(rules)
+	return strings.Join(rules, "\n")
+}
+
+// lookup returns the record of the directory at path if it hasn't changed
+func (c *ScanCache) lookup(path string, id fileID, modTime time.Time) *dirRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, ok := c.seen[path]
+	if !ok {
+		rec = c.old[path]
+	}
+	if rec == nil || rec.Dev != id.dev || rec.Ino != id.ino || rec.ModTime != modTime.UnixNano() {
+		return nil
+	}
+	c.seen[path] = rec
+	return rec
+}
+
+// store remembers a freshly built record
+func (c *ScanCache) store(path string, rec *dirRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[path] = rec
+}
+
+// save writes the cache to disk. Records below roots that weren't used
+// during the scan are gone and get dropped; the rest of the old records,
+// from targets this scan didn't cover, are kept.
+func (c *ScanCache) save(roots []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dirs := make(map[string]*dirRecord, len(c.seen))
+	for path, rec := range c.old {
+		covered := false
+		for _, root := range roots {
+			if Within(path, root) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			dirs[path] = rec
+		}
+	}
+	for path, rec := range c.seen {
+		dirs[path] = rec
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), CacheFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = gob.NewEncoder(tmp).Encode(cacheFile{Version: cacheVersion, Key: c.key, Dirs: dirs})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.old = dirs
+	c.seen = make(map[string]*dirRecord)
+	return nil
+}
+
+// addFile counts one file of the directory. With links, files that have
+// other hard links are kept apart for linkTracker.
+func (r *dirRecord) addFile(info os.FileInfo, links bool) {
+	r.Files++
+	mod := info.ModTime().UnixNano()
+	if r.Newest == 0 || mod > r.Newest {
+		r.Newest = mod
+	}
+	if r.Oldest == 0 || mod < r.Oldest {
+		r.Oldest = mod
+	}
+
+	if id, nlink, ok := fileIdentity(info); ok && nlink > 1 && links {
+		r.Links = append(r.Links, cachedLink{
+			Dev: id.dev, Ino: id.ino, Nlink: nlink,
+			Size: info.Size(), Alloc: allocatedSize(info), ModTime: mod,
+		})
+		return
+	}
+	r.Size += info.Size()
+	r.Alloc += allocatedSize(info)
+	if links && info.Size() >= largeFileMin {
+		r.Large = append(r.Large, newCachedFile(info))
+	}
+	if r.Hours == nil {
+		r.Hours = make(map[int64]AgeBucket)
+	}
+	hour := info.ModTime().Unix() / 3600
+	b := r.Hours[hour]
+	b.Files++
+	b.Size += info.Size()
+	r.Hours[hour] = b
+}
+
+// trimLarge keeps only the LargestFiles largest of the directory's files;
+// no others can make the list
+func (r *dirRecord) trimLarge() {
+	if len(r.Large) <= LargestFiles {
+		return
+	}
+	sort.Slice(r.Large, func(i, j int) bool { return r.Large[i].Size > r.Large[j].Size })
+	r.Large = r.Large[:LargestFiles]
+}
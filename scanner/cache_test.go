@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// cachedRecord looks up the cache record of dir as a scan would
+func cachedRecord(t *testing.T, c *ScanCache, dir string) *dirRecord {
+	t.Helper()
+	info, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, _ := fileIdentity(info)
+	return c.lookup(dir, id, info.ModTime())
+}
+
+// cachedScanner returns a fixture scanner with its cache loaded from disk,
+// as the next run of dusty would see it
+func cachedScanner(f *fixture) *Scanner {
+	s := f.scanner()
+	s.Cache = s.LoadCache()
+	return s
+}
+
+func TestScanCacheReusesUnchangedDirectories(t *testing.T) {
+	f := newFixture(t)
+	dir := filepath.Join(f.root, "pkg")
+	writeFile(t, filepath.Join(dir, "a"), 1000)
+	scanEntry(t, cachedScanner(f), f.root)
+
+	s := cachedScanner(f)
+	if cachedRecord(t, s.Cache, dir) == nil {
+		t.Fatal("unchanged directory isn't reused from the saved cache")
+	}
+	if got := scanEntry(t, s, f.root).Size; got != 1000 {
+		t.Errorf("size from cache = %d, want 1000", got)
+	}
+}
+
+func TestScanCacheRereadsChangedDirectories(t *testing.T) {
+	f := newFixture(t)
+	added := filepath.Join(f.root, "added")
+	replaced := filepath.Join(f.root, "replaced")
+	writeFile(t, filepath.Join(added, "a"), 1000)
+	writeFile(t, filepath.Join(replaced, "a"), 1000)
+	scanEntry(t, cachedScanner(f), f.root)
+
+	// A new file changes its directory's mtime
+	writeFile(t, filepath.Join(added, "b"), 500)
+	// A directory made anew is another inode, even with the old mtime
+	info, err := os.Lstat(replaced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep the old one until the new one exists, so its inode isn't reused
+	old := filepath.Join(f.outside, "old")
+	if err := os.Rename(replaced, old); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(replaced, "a"), 3000)
+	if err := os.RemoveAll(old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(replaced, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := cachedScanner(f)
+	for _, dir := range []string{added, replaced} {
+		if cachedRecord(t, s.Cache, dir) != nil {
+			t.Errorf("changed directory %s is reused from the cache", dir)
+		}
+	}
+	if got := scanEntry(t, s, f.root).Size; got != 1500+3000 {
+		t.Errorf("size after changes = %d, want %d", got, 1500+3000)
+	}
+}
+
+func TestScanCacheSaveDropsRemovedDirectories(t *testing.T) {
+	f := newFixture(t)
+	gone := filepath.Join(f.root, "gone")
+	writeFile(t, filepath.Join(gone, "a"), 100)
+	writeFile(t, filepath.Join(f.root, "kept", "b"), 100)
+	s := cachedScanner(f)
+	// A record from a target this scan doesn't cover
+	elsewhere := filepath.Join(f.outside, "dir")
+	s.Cache.store(elsewhere, &dirRecord{})
+	scanEntry(t, s, f.root)
+
+	if err := os.RemoveAll(gone); err != nil {
+		t.Fatal(err)
+	}
+	scanEntry(t, cachedScanner(f), f.root)
+
+	old := cachedScanner(f).Cache.old
+	if _, ok := old[gone]; ok {
+		t.Error("record of a removed directory survives the save")
+	}
+	if _, ok := old[filepath.Join(f.root, "kept")]; !ok {
+		t.Error("record of a scanned directory isn't saved")
+	}
+	if _, ok := old[elsewhere]; !ok {
+		t.Error("record outside the scanned targets is dropped")
+	}
+}
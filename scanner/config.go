@@ -0,0 +1,227 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFile is the name of the config file inside ConfigDir
+const ConfigFile = "config.json"
+
+// Config is the user configuration file. Its targets are merged with the
+// built-in catalog by GetAllowedPaths.
+//
+//	{
+//	  "exclude": ["*.license", "~/Library/Caches/com.apple.amsengagementd"],
+//	  "targets": [
+//	    {"path": "~/work/.mirror", "description": "Artifact Mirror", "category": "Developer"},
+//	    {"path": "$XDG_CACHE_HOME/thumbnails", "enabled": false},
+//	    {"path": "~/Library/Caches", "exclude": ["com.adobe.*/License*"]}
+//	  ],
+//	  "profiles": [
+//	    {"name": "Work", "categories": ["Developer"], "targets": ["Artifact Mirror"]}
+//	  ],
+//	  "projects": ["~/src"]
+//	}
+type Config struct {
+	Exclude  []string       `json:"exclude"` // Glob patterns never scanned or cleaned, in any target
+	Targets  []TargetConfig `json:"targets"`
+	Profiles []Profile      `json:"profiles"` // Added to, or replacing, the built-in profiles
+	Projects []string       `json:"projects"` // Roots searched for build artifacts by FindArtifacts
+}
+
+// TargetConfig declares a target in the config file. A path matching a
+// built-in target overrides its non-empty fields; any other path adds a new
+// target.
+type TargetConfig struct {
+	Path        string   `json:"path"` // Supports a leading ~ and $VAR / ${VAR}
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Risk        string   `json:"risk"`
+	Enabled     *bool    `json:"enabled"` // Defaults to true
+	Exclude     []string `json:"exclude"` // Glob patterns, relative ones anchored at path
+}
+
+// enabled reports whether t is switched on, the default
+func (t TargetConfig) enabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+// ConfigDir returns dusty's config directory, $XDG_CONFIG_HOME/dusty
+// (~/.config/dusty by default) on every platform
+func (s *Scanner) ConfigDir() string {
+	return filepath.Join(s.xdgDir("XDG_CONFIG_HOME", ".config"), "dusty")
+}
+
+// LoadConfig reads and validates the config file at path, expanding target
+// paths against home. A missing file is not an error and yields an empty
+// config. Every invalid entry is reported, not just the first.
+func LoadConfig(path, home string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	for i, pattern := range cfg.Exclude {
+		if _, err := compileExclude(pattern, "", home); err != nil {
+			errs = append(errs, fmt.Errorf("%s: exclude[%d]: %w", path, i, err))
+		}
+	}
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		expanded, err := expandPath(t.Path, home)
+		if err == nil {
+			err = checkTargetPath(expanded, home)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: targets[%d] (%q): %w", path, i, t.Path, err))
+			continue
+		}
+		t.Path = expanded
+		for j, pattern := range t.Exclude {
+			if _, err := compileExclude(pattern, t.Path, home); err != nil {
+				errs = append(errs, fmt.Errorf("%s: targets[%d].exclude[%d]: %w", path, i, j, err))
+			}
+		}
+	}
+	for i, root := range cfg.Projects {
+		expanded, err := expandPath(root, home)
+		if err == nil && !filepath.IsAbs(expanded) {
+			err = errors.New("path must be absolute or start with ~")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: projects[%d] (%q): %w", path, i, root, err))
+			continue
+		}
+		cfg.Projects[i] = expanded
+	}
+	seen := make(map[string]bool)
+	for i := range cfg.Profiles {
+		p := &cfg.Profiles[i]
+		if err := p.validate(home); err != nil {
+			errs = append(errs, fmt.Errorf("%s: profiles[%d] (%q): %w", path, i, p.Name, err))
+			continue
+		}
+		if key := strings.ToLower(p.Name); seen[key] {
+			errs = append(errs, fmt.Errorf("%s: profiles[%d]: duplicate profile %q", path, i, p.Name))
+		} else {
+			seen[key] = true
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &cfg, nil
+}
+
+// expandPath expands a leading ~ and environment variables in path. Unset
+// variables are an error rather than silently becoming empty.
+func expandPath(path, home string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", errors.New("path is required")
+	}
+
+	var missing []string
+	path = os.Expand(path, func(name string) string {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			return v
+		}
+		// XDG base directories have well-known defaults
+		switch name {
+		case "HOME":
+			return home
+		case "XDG_CACHE_HOME":
+			return filepath.Join(home, ".cache")
+		case "XDG_CONFIG_HOME":
+			return filepath.Join(home, ".config")
+		case "XDG_DATA_HOME":
+			return filepath.Join(home, ".local", "share")
+		}
+		missing = append(missing, name)
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined environment variable %s", strings.Join(missing, ", "))
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = filepath.Join(home, path[1:])
+	}
+	return filepath.Clean(path), nil
+}
+
+// checkTargetPath rejects target paths that would be dangerous to clean
+func checkTargetPath(path, home string) error {
+	if !filepath.IsAbs(path) {
+		return errors.New("path must be absolute or start with ~")
+	}
+	if Within(home, path) {
+		return errors.New("path can't be the home directory or one of its parents")
+	}
+	return nil
+}
+
+// merge applies the configured targets on top of builtin and drops the
+// disabled ones
+func (c *Config) merge(builtin []Target) []Target {
+	targets := append([]Target(nil), builtin...)
+	index := make(map[string]int, len(targets))
+	for i, t := range targets {
+		index[t.Path] = i
+	}
+	disabled := make(map[string]bool)
+
+	for _, tc := range c.Targets {
+		disabled[tc.Path] = !tc.enabled()
+		i, ok := index[tc.Path]
+		if !ok {
+			description := tc.Description
+			if description == "" {
+				description = filepath.Base(tc.Path)
+			}
+			index[tc.Path] = len(targets)
+			targets = append(targets, Target{
+				Path:        tc.Path,
+				Description: description,
+				Category:    tc.Category,
+				Risk:        tc.Risk,
+			})
+			continue
+		}
+		t := &targets[i]
+		if tc.Description != "" {
+			t.Description = tc.Description
+		}
+		if tc.Category != "" {
+			t.Category = tc.Category
+		}
+		if tc.Risk != "" {
+			t.Risk = tc.Risk
+		}
+	}
+
+	var enabled []Target
+	for _, t := range targets {
+		if !disabled[t.Path] {
+			enabled = append(enabled, t)
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadConfig writes data as a config file and loads it against home
+func loadConfig(t *testing.T, home, data string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ConfigFile)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(path, home)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	t.Setenv("DUSTY_TEST_UNSET", "") // Empty counts as unset
+	home := "/home/tester"
+	tests := []struct {
+		name string
+		data string
+		want []string // Every one must appear in the error
+	}{
+		{"relative path", `{"targets": [{"path": "cache/x"}]}`,
+			[]string{`targets[0] ("cache/x")`, "path must be absolute or start with ~"}},
+		{"empty path", `{"targets": [{"description": "Nothing"}]}`,
+			[]string{"targets[0]", "path is required"}},
+		{"unset variable", `{"targets": [{"path": "$DUSTY_TEST_UNSET/cache"}]}`,
+			[]string{"undefined environment variable DUSTY_TEST_UNSET"}},
+		{"home directory", `{"targets": [{"path": "~"}]}`,
+			[]string{"can't be the home directory or one of its parents"}},
+		{"parent of home", `{"targets": [{"path": "/home"}]}`,
+			[]string{"can't be the home directory or one of its parents"}},
+		{"unknown field", `{"targetz": []}`,
+			[]string{`unknown field "targetz"`}},
+		{"bad exclude", `{"exclude": ["[oops"]}`,
+			[]string{"exclude[0]", "syntax error in pattern"}},
+		{"relative project", `{"projects": ["src"]}`,
+			[]string{`projects[0] ("src")`, "path must be absolute"}},
+		{"duplicate profile", `{"profiles": [{"name": "Work", "categories": ["Developer"]}, {"name": "work", "categories": ["Logs"]}]}`,
+			[]string{`duplicate profile "work"`}},
+		{"several", `{
+			"exclude": ["", "*.ok"],
+			"targets": [{"path": "~/fine"}, {"path": "relative"}, {"path": "~", "exclude": ["[bad/x"]}]
+		}`, []string{
+			"exclude[0]: exclude pattern is empty",
+			`targets[1] ("relative")`,
+			`targets[2] ("~")`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadConfig(t, home, tt.data)
+			if err == nil {
+				t.Fatalf("loaded %+v, want an error", cfg)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't mention %q", err, want)
+				}
+			}
+			if !strings.Contains(err.Error(), ConfigFile) {
+				t.Errorf("error %q doesn't name the file", err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExpandsPaths(t *testing.T) {
+	t.Setenv("DUSTY_TEST_DIR", "/srv/mirror")
+	t.Setenv("XDG_CACHE_HOME", "")
+	home := "/home/tester"
+	cfg, err := loadConfig(t, home, `{
+		"targets": [
+			{"path": "~/work/.mirror/"},
+			{"path": "$DUSTY_TEST_DIR/cache"},
+			{"path": "${XDG_CACHE_HOME}/thumbnails", "enabled": false}
+		],
+		"projects": ["~/src"]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/home/tester/work/.mirror", "/srv/mirror/cache", "/home/tester/.cache/thumbnails"}
+	for i, w := range want {
+		if got := cfg.Targets[i].Path; got != w {
+			t.Errorf("targets[%d] = %q, want %q", i, got, w)
+		}
+	}
+	if cfg.Targets[2].enabled() {
+		t.Error("targets[2] is enabled")
+	}
+	if len(cfg.Projects) != 1 || cfg.Projects[0] != "/home/tester/src" {
+		t.Errorf("projects = %q", cfg.Projects)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), ConfigFile), "/home/tester")
+	if err != nil || len(cfg.Targets) != 0 {
+		t.Errorf("LoadConfig = %+v, %v; want an empty config", cfg, err)
+	}
+}
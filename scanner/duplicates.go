@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DuplicateMinSize is the smallest file FindDuplicates looks at; smaller
+// copies aren't worth the reads
+const DuplicateMinSize = 64 << 10
+
+// partialHashSize is how much of each file the first hashing pass reads
+const partialHashSize = 16 << 10
+
+// DuplicateGroup is a set of files with the same content
+type DuplicateGroup struct {
+	Size      int64         // Size of each copy
+	AllocSize int64         // On-disk size of each copy
+	Files     []*CacheEntry // The copies, ordered by path
+}
+
+// Reclaimable returns the on-disk bytes freed by keeping a single copy
+func (g DuplicateGroup) Reclaimable() int64 {
+	return g.AllocSize * int64(len(g.Files)-1)
+}
+
+// FindDuplicates looks for files with the same content across the targets.
+// Candidates are grouped by size, then by a hash of their first bytes, and
+// only files still alike are hashed in full. Excluded items, symlinks and
+// files that are hard links of each other aren't duplicates. Groups come
+// back with the most reclaimable bytes first.
+func (s *Scanner) FindDuplicates(ctx context.Context) ([]DuplicateGroup, error) {
+	bySize := make(map[int64][]*CacheEntry)
+	seen := make(map[string]bool)   // Paths, as nested targets overlap
+	inodes := make(map[fileID]bool) // Hard links are one file
+	ex := s.exclusions()
+	now := time.Now()
+
+	// Inner targets go first, so files in nested targets belong to the
+	// innermost one and the outer walk skips them
+	targets := s.GetAllowedPaths()
+	sort.SliceStable(targets, func(i, j int) bool { return len(targets[i].Path) > len(targets[j].Path) })
+	for _, target := range targets {
+		var rootDev fileID
+		err := filepath.WalkDir(target.Path, func(p string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				return nil // Unreadable or missing; skip it
+			}
+			if _, ok := ex.match(p); ok {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				info, err := d.Info()
+				if err != nil {
+					return filepath.SkipDir
+				}
+				if p == target.Path {
+					rootDev, _, _ = fileIdentity(info)
+				} else if s.OneFileSystem && !sameDevice(info, rootDev) {
+					return filepath.SkipDir
+				}
+				if seen[p] {
+					return filepath.SkipDir // Walked as part of an enclosing target
+				}
+				seen[p] = true
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || info.Size() < DuplicateMinSize {
+				return nil
+			}
+			if id, _, ok := fileIdentity(info); ok {
+				if inodes[id] {
+					return nil
+				}
+				inodes[id] = true
+			}
+			e := &CacheEntry{
+				Name:        d.Name(),
+				Path:        p,
+				Size:        info.Size(),
+				AllocSize:   allocatedSize(info),
+				FileCount:   1,
+				LastMod:     info.ModTime(),
+				OldestMod:   info.ModTime(),
+				Description: target.Description,
+				Category:    target.Category,
+				Risk:        target.Risk,
+			}
+			e.Ages.Add(info.ModTime(), now, info.Size())
+			bySize[info.Size()] = append(bySize[info.Size()], e)
+			return nil
+		})
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	var groups []DuplicateGroup
+	for _, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+		for _, partial := range s.splitByHash(ctx, files, partialHashSize) {
+			for _, same := range s.splitByHash(ctx, partial, -1) {
+				sort.Slice(same, func(i, j int) bool { return same[i].Path < same[j].Path })
+				groups = append(groups, DuplicateGroup{Size: same[0].Size, AllocSize: same[0].AllocSize, Files: same})
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if a, b := groups[i].Reclaimable(), groups[j].Reclaimable(); a != b {
+			return a > b
+		}
+		return groups[i].Files[0].Path < groups[j].Files[0].Path
+	})
+	return groups, nil
+}
+
+// splitByHash hashes the first limit bytes of each file, all of it if limit
+// is negative, and returns the sets of two or more files that hash alike.
+// Files that can't be read drop out.
+func (s *Scanner) splitByHash(ctx context.Context, files []*CacheEntry, limit int64) [][]*CacheEntry {
+	if limit >= 0 && files[0].Size <= limit {
+		return [][]*CacheEntry{files} // The full hash reads them anyway
+	}
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	sem := make(chan struct{}, workers)
+	sums := make([]string, len(files))
+	var wg sync.WaitGroup
+	for i, e := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			sums[i] = hashFile(e.Path, limit)
+		})
+	}
+	wg.Wait()
+
+	byHash := make(map[string][]*CacheEntry)
+	var order []string
+	for i, sum := range sums {
+		if sum == "" {
+			continue
+		}
+		if _, ok := byHash[sum]; !ok {
+			order = append(order, sum)
+		}
+		byHash[sum] = append(byHash[sum], files[i])
+	}
+	var sets [][]*CacheEntry
+	for _, sum := range order {
+		if len(byHash[sum]) > 1 {
+			sets = append(sets, byHash[sum])
+		}
+	}
+	return sets
+}
+
+// hashFile returns the SHA-256 of the first limit bytes of the file at path,
+// or of all of it if limit is negative. It returns "" if the file can't be
+// read.
+func hashFile(path string, limit int64) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return ""
+	}
+	return string(h.Sum(nil))
+}
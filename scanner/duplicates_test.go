@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeBytes(t *testing.T, path string, data []byte) {
+	t.Helper()
+	mkdir(t, filepath.Dir(path))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// content returns size bytes of fill, with tail as the last byte
+func content(size int, fill, tail byte) []byte {
+	data := bytes.Repeat([]byte{fill}, size)
+	data[size-1] = tail
+	return data
+}
+
+func groupPaths(groups []DuplicateGroup) [][]string {
+	var all [][]string
+	for _, g := range groups {
+		var paths []string
+		for _, f := range g.Files {
+			paths = append(paths, f.Path)
+		}
+		all = append(all, paths)
+	}
+	return all
+}
+
+func TestFindDuplicates(t *testing.T) {
+	f := newFixture(t)
+	var pip string
+	for _, target := range f.scanner().GetAllowedPaths() {
+		if target.Description == "Python pip Cache" {
+			pip = target.Path
+		}
+	}
+	size := 2 * DuplicateMinSize
+
+	// The same content in two targets, and twice in one
+	same := content(size, 'a', 'a')
+	writeBytes(t, filepath.Join(f.root, "one.tgz"), same)
+	writeBytes(t, filepath.Join(f.root, "sub", "two.tgz"), same)
+	writeBytes(t, filepath.Join(pip, "three.tgz"), same)
+	// Same size and first bytes, different ending
+	writeBytes(t, filepath.Join(f.root, "tail.tgz"), content(size, 'a', 'b'))
+	// Same size, different from the start
+	writeBytes(t, filepath.Join(f.root, "other.tgz"), content(size, 'c', 'a'))
+	// A hard link is the same file, not a copy
+	link(t, filepath.Join(f.root, "one.tgz"), filepath.Join(f.root, "linked.tgz"))
+	// Empty and small files are never worth it
+	writeBytes(t, filepath.Join(f.root, "empty1"), nil)
+	writeBytes(t, filepath.Join(f.root, "empty2"), nil)
+	writeBytes(t, filepath.Join(f.root, "small1"), content(100, 's', 's'))
+	writeBytes(t, filepath.Join(f.root, "small2"), content(100, 's', 's'))
+
+	groups, err := f.scanner().FindDuplicates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("groups = %q, want one", groupPaths(groups))
+	}
+	g := groups[0]
+	var names []string
+	for _, file := range g.Files {
+		names = append(names, file.Name)
+	}
+	// one.tgz or linked.tgz stands for both, whichever was walked first
+	if len(names) != 3 || !slices.Contains(names, "two.tgz") || !slices.Contains(names, "three.tgz") ||
+		slices.Contains(names, "one.tgz") == slices.Contains(names, "linked.tgz") {
+		t.Errorf("copies = %q, want two.tgz, three.tgz and one of the two links", names)
+	}
+	if g.Size != int64(size) || g.Reclaimable() != 2*g.AllocSize {
+		t.Errorf("size %d, reclaimable %d; want %d and two copies' worth", g.Size, g.Reclaimable(), size)
+	}
+	for _, file := range g.Files {
+		if want := Within(file.Path, pip); (file.Description == "Python pip Cache") != want {
+			t.Errorf("%s described as %q", file.Path, file.Description)
+		}
+	}
+}
+
+func TestSplitByHash(t *testing.T) {
+	dir := t.TempDir()
+	var files []*CacheEntry
+	for _, name := range []string{"a1", "b", "a2", "gone"} {
+		path := filepath.Join(dir, name)
+		files = append(files, &CacheEntry{Path: path, Size: 10})
+		if name == "gone" {
+			continue // Unreadable files drop out
+		}
+		writeBytes(t, path, content(10, name[0], 'x'))
+	}
+	s := &Scanner{Workers: 2}
+
+	sets := s.splitByHash(context.Background(), files, -1)
+	if len(sets) != 1 || !slices.Equal(sets[0], []*CacheEntry{files[0], files[2]}) {
+		t.Errorf("sets = %v, want a1 and a2 alone", sets)
+	}
+	// Files no longer than the partial hash are left to the full one
+	if sets := s.splitByHash(context.Background(), files, 10); len(sets) != 1 || len(sets[0]) != 4 {
+		t.Errorf("partial sets = %v, want all files passed through", sets)
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	writeBytes(t, a, content(100, 'x', 'a'))
+	writeBytes(t, b, content(100, 'x', 'b'))
+
+	if hashFile(a, -1) == hashFile(b, -1) {
+		t.Error("different files hash alike")
+	}
+	if hashFile(a, 50) != hashFile(b, 50) {
+		t.Error("files with the same first 50 bytes hash differently over them")
+	}
+	if hashFile(filepath.Join(dir, "missing"), -1) != "" {
+		t.Error("missing file has a hash")
+	}
+}
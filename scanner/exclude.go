@@ -0,0 +1,257 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errExcludedFound stops a walk as soon as an excluded path is found
+var errExcludedFound = errors.New("excluded path found")
+
+// excludeRule is a compiled exclusion pattern. A pattern without a slash
+// matches a file or directory name at any depth ("*.license"). A pattern
+// with a slash matches whole paths: absolute ones (after ~ and $VAR
+// expansion) as written, relative ones below the target root, or anywhere
+// for global rules. "**" matches any number of directories.
+type excludeRule struct {
+	pattern  string // As written, for messages
+	segments []string
+	nameOnly bool
+}
+
+// compileExclude compiles pattern. Relative patterns with a slash are
+// anchored at base, or float when base is empty.
+func compileExclude(pattern, base, home string) (excludeRule, error) {
+	r := excludeRule{pattern: pattern}
+	if strings.TrimSpace(pattern) == "" {
+		return r, errors.New("exclude pattern is empty")
+	}
+
+	if !strings.Contains(pattern, "/") {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return r, fmt.Errorf("exclude pattern %q: %w", pattern, err)
+		}
+		r.nameOnly = true
+		r.segments = []string{pattern}
+		return r, nil
+	}
+
+	expanded, err := expandPath(pattern, home)
+	if err != nil {
+		return r, fmt.Errorf("exclude pattern %q: %w", pattern, err)
+	}
+	if !filepath.IsAbs(expanded) {
+		if base == "" {
+			expanded = filepath.Join("**", expanded)
+		} else {
+			expanded = filepath.Join(base, expanded)
+		}
+	}
+	r.segments = splitPath(expanded)
+	for _, seg := range r.segments {
+		if _, err := filepath.Match(seg, ""); err != nil {
+			return r, fmt.Errorf("exclude pattern %q: %w", pattern, err)
+		}
+	}
+	return r, nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(filepath.ToSlash(path), "/"), "/")
+}
+
+// match reports whether path itself matches the rule
+func (r excludeRule) match(path string) bool {
+	if r.nameOnly {
+		ok, _ := filepath.Match(r.segments[0], filepath.Base(path))
+		return ok
+	}
+	return matchSegments(r.segments, splitPath(path))
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// exclusions holds every exclude rule of a Scanner
+type exclusions struct {
+	global  []excludeRule
+	targets map[string][]excludeRule // By target root
+}
+
+// exclusions compiles the global and per-target exclude rules once. Patterns
+// were validated by LoadConfig, so compile errors can't happen here for
+// config-provided rules; any that do are ignored.
+func (s *Scanner) exclusions() *exclusions {
+	s.excludeOnce.Do(func() {
+		ex := &exclusions{targets: make(map[string][]excludeRule)}
+		s.excluded = ex
+		if s.Config == nil {
+			return
+		}
+		for _, p := range s.Config.Exclude {
+			if r, err := compileExclude(p, "", s.HomeDir); err == nil {
+				ex.global = append(ex.global, r)
+			}
+		}
+		for _, t := range s.Config.Targets {
+			for _, p := range t.Exclude {
+				if r, err := compileExclude(p, t.Path, s.HomeDir); err == nil {
+					ex.targets[t.Path] = append(ex.targets[t.Path], r)
+				}
+			}
+		}
+	})
+	return s.excluded
+}
+
+func (e *exclusions) empty() bool {
+	return len(e.global) == 0 && len(e.targets) == 0
+}
+
+// match returns the rule that matches path itself, if any
+func (e *exclusions) match(path string) (excludeRule, bool) {
+	for _, r := range e.global {
+		if r.match(path) {
+			return r, true
+		}
+	}
+	for root, rules := range e.targets {
+		if path == root || !Within(path, root) {
+			continue
+		}
+		for _, r := range rules {
+			if r.match(path) {
+				return r, true
+			}
+		}
+	}
+	return excludeRule{}, false
+}
+
+// excludedBy returns the rule excluding path or one of its parent
+// directories, if any
+func (s *Scanner) excludedBy(path string) (excludeRule, bool) {
+	ex := s.exclusions()
+	if ex.empty() {
+		return excludeRule{}, false
+	}
+	for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+		if r, ok := ex.match(p); ok {
+			return r, true
+		}
+		if parent := filepath.Dir(p); parent == p {
+			return excludeRule{}, false
+		}
+	}
+}
+
+// IsExcluded reports whether path, or a directory containing it, matches an
+// exclude rule
+func (s *Scanner) IsExcluded(path string) bool {
+	_, ok := s.excludedBy(path)
+	return ok
+}
+
+// containsExcluded reports whether anything below path is excluded
+func (s *Scanner) containsExcluded(path string) bool {
+	ex := s.exclusions()
+	if ex.empty() {
+		return false
+	}
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == path {
+			return nil
+		}
+		if _, ok := ex.match(p); ok {
+			return errExcludedFound
+		}
+		return nil
+	})
+	return errors.Is(err, errExcludedFound)
+}
+
+// CleanablePaths returns what to delete to clean path without touching
+// excluded items: path itself when nothing in it is excluded, otherwise its
+// non-excluded contents, recursively. It returns nothing for an excluded path.
+// The tree is walked once, so check the result with CheckCleanablePath.
+func (s *Scanner) CleanablePaths(path string) ([]string, error) {
+	if s.IsExcluded(path) {
+		return nil, nil
+	}
+	ex := s.exclusions()
+	if ex.empty() {
+		return []string{path}, nil
+	}
+
+	// Note every directory with an excluded item somewhere below it
+	holds := make(map[string]bool)
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == path {
+			return nil
+		}
+		if _, ok := ex.match(p); !ok {
+			return nil
+		}
+		for dir := filepath.Dir(p); !holds[dir]; dir = filepath.Dir(dir) {
+			holds[dir] = true
+			if dir == path {
+				break
+			}
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collectCleanable(ex, path, holds)
+}
+
+// collectCleanable returns path if nothing below it is excluded, per holds,
+// or else its non-excluded contents
+func collectCleanable(ex *exclusions, path string, holds map[string]bool) ([]string, error) {
+	if !holds[path] {
+		return []string{path}, nil
+	}
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, de := range dirEntries {
+		sub := filepath.Join(path, de.Name())
+		if _, ok := ex.match(sub); ok {
+			continue
+		}
+		subPaths, err := collectCleanable(ex, sub, holds)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, subPaths...)
+	}
+	return paths, nil
+}
@@ -0,0 +1,153 @@
+package scanner
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExcludeRuleMatch(t *testing.T) {
+	home := "/home/u"
+	caches := "/home/u/Library/Caches"
+	tests := []struct {
+		pattern, base, path string
+		want                bool
+	}{
+		// No slash: a name at any depth
+		{"*.license", "", "/home/u/.cache/app/x.license", true},
+		{"*.license", "", "/home/u/.cache/app/x.lic", false},
+		{"node_modules", "", "/src/a/node_modules", true},
+
+		// Absolute, after ~ expansion
+		{"~/Library/Caches/com.apple.x", "", "/home/u/Library/Caches/com.apple.x", true},
+		{"~/Library/Caches/com.apple.x", "", "/home/u/Library/Caches/com.apple.x/sub", false},
+		{"/opt/cache/*/keep", "", "/opt/cache/a/keep", true},
+		{"/opt/cache/*/keep", "", "/opt/cache/a/b/keep", false},
+
+		// Relative global patterns float
+		{"foo/bar", "", "/x/y/foo/bar", true},
+		{"foo/bar", "", "/x/foo/bar/baz", false},
+
+		// Relative target patterns are anchored at the target
+		{"com.adobe.*/License*", caches, caches + "/com.adobe.acrobat/License.db", true},
+		{"com.adobe.*/License*", caches, caches + "/sub/com.adobe.acrobat/License.db", false},
+
+		// ** matches any number of directories, including none
+		{"/a/**/b", "", "/a/b", true},
+		{"/a/**/b", "", "/a/x/y/b", true},
+		{"/a/**/b", "", "/a/x/y/c", false},
+		{"/a/**", "", "/a", true},
+		{"/a/**", "", "/a/x/y", true},
+		{"/a/**/x/**/y", "", "/a/1/x/2/3/y", true},
+		{"/a/**/x/**/y", "", "/a/1/2/3/y", false},
+	}
+	for _, tt := range tests {
+		r, err := compileExclude(tt.pattern, tt.base, home)
+		if err != nil {
+			t.Errorf("compileExclude(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := r.match(tt.path); got != tt.want {
+			t.Errorf("%q (base %q) matching %s = %v, want %v", tt.pattern, tt.base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompileExcludeErrors(t *testing.T) {
+	for _, pattern := range []string{"", "  ", "[oops", "/a/[b/c"} {
+		if _, err := compileExclude(pattern, "", "/home/u"); err == nil {
+			t.Errorf("compileExclude(%q) succeeded", pattern)
+		}
+	}
+}
+
+// excludeFixture has a yarn cache with *.keep files excluded: a/x is free
+// to go, b holds b/y.keep among other things, and c.keep is excluded itself
+func excludeFixture(t *testing.T) (*fixture, *Scanner) {
+	t.Helper()
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "a", "x"), 10)
+	writeFile(t, filepath.Join(f.root, "b", "y.keep"), 10)
+	writeFile(t, filepath.Join(f.root, "b", "z"), 10)
+	writeFile(t, filepath.Join(f.root, "b", "sub", "w"), 10)
+	writeFile(t, filepath.Join(f.root, "c.keep"), 10)
+	s := f.scanner()
+	s.Config = &Config{Exclude: []string{"*.keep"}}
+	return f, s
+}
+
+func TestCleanablePaths(t *testing.T) {
+	f, s := excludeFixture(t)
+	got, err := s.CleanablePaths(f.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(f.root, "a"),
+		filepath.Join(f.root, "b", "sub"),
+		filepath.Join(f.root, "b", "z"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CleanablePaths = %q, want %q", got, want)
+	}
+
+	a := filepath.Join(f.root, "a")
+	if got, _ := s.CleanablePaths(a); !slices.Equal(got, []string{a}) {
+		t.Errorf("CleanablePaths(a) = %q, want a itself", got)
+	}
+	if got, _ := s.CleanablePaths(filepath.Join(f.root, "c.keep")); len(got) != 0 {
+		t.Errorf("CleanablePaths(c.keep) = %q, want nothing", got)
+	}
+}
+
+func TestCheckCleanPathRefusesExcluded(t *testing.T) {
+	f, s := excludeFixture(t)
+	tests := []struct {
+		path string
+		want string // Empty if allowed
+	}{
+		{f.root, "contains excluded items"},
+		{filepath.Join(f.root, "b"), "contains excluded items"},
+		{filepath.Join(f.root, "b", "y.keep"), `excluded by "*.keep"`},
+		{filepath.Join(f.root, "a"), ""},
+		{filepath.Join(f.root, "b", "z"), ""},
+	}
+	for _, tt := range tests {
+		err := s.CheckCleanPath(tt.path)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("CheckCleanPath(%s): %v", tt.path, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("CheckCleanPath(%s) = %v, want %q", tt.path, err, tt.want)
+		}
+	}
+	// Still refused for what an exclude rule names, without the walk
+	if err := s.CheckCleanablePath(filepath.Join(f.root, "c.keep")); err == nil {
+		t.Error("CheckCleanablePath allowed an excluded file")
+	}
+}
+
+func TestCleanKeepsExcludedItems(t *testing.T) {
+	f, s := excludeFixture(t)
+	root := scanEntry(t, s, f.root)
+	if root.Size != 30 || root.ExcludedSize != 20 {
+		t.Errorf("root = %d bytes, %d excluded; want 30 and 20", root.Size, root.ExcludedSize)
+	}
+
+	report := pathModule{}.Clean(context.Background(), s, []*CacheEntry{root}, CleanOptions{})
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b/z", "b/sub"} {
+		if exists(filepath.Join(f.root, name)) {
+			t.Errorf("%s wasn't cleaned", name)
+		}
+	}
+	for _, name := range []string{"b/y.keep", "c.keep"} {
+		if !exists(filepath.Join(f.root, name)) {
+			t.Errorf("excluded %s was cleaned", name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Reasons a build artifact inside a git working tree can't be cleaned
+var (
+	errNotIgnored = errors.New("not ignored by git; it may hold untracked work")
+	errTracked    = errors.New("git tracks files inside it")
+)
+
+// gitRepoOf returns the git working tree containing path, or "" if there's
+// none. A .git file, as in worktrees and submodules, counts too.
+func gitRepoOf(path string) string {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
+
+// gitCheckIgnored asks git in repo whether each of dirs, all inside it, is
+// ignored with nothing tracked inside. The result holds the reason for
+// every dir that fails, and err is set if git itself can't be run.
+func gitCheckIgnored(ctx context.Context, repo string, dirs []string) (map[string]error, error) {
+	rel := make([]string, len(dirs))
+	for i, dir := range dirs {
+		r, err := filepath.Rel(repo, dir)
+		if err != nil {
+			return nil, err
+		}
+		rel[i] = filepath.ToSlash(r)
+	}
+
+	// check-ignore prints the ignored paths and exits 1 if there are none.
+	// Without --no-index, a tracked file inside would hide the rule; those
+	// are found by ls-files below.
+	out, err := runGit(ctx, repo, strings.Join(rel, "\x00")+"\x00", "check-ignore", "-z", "--stdin", "--no-index")
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, err
+	}
+	ignored := make(map[string]bool)
+	for _, p := range splitNul(out) {
+		ignored[p] = true
+	}
+
+	out, err = runGit(ctx, repo, "", append([]string{"ls-files", "-z", "--"}, rel...)...)
+	if err != nil {
+		return nil, err
+	}
+	tracked := splitNul(out)
+
+	failed := make(map[string]error)
+	for i, r := range rel {
+		switch {
+		case !ignored[r]:
+			failed[dirs[i]] = errNotIgnored
+		case hasPathPrefix(tracked, r):
+			failed[dirs[i]] = errTracked
+		}
+	}
+	return failed, nil
+}
+
+// gitDirty reports whether repo has uncommitted changes or untracked files
+func gitDirty(ctx context.Context, repo string) (bool, error) {
+	out, err := runGit(ctx, repo, "", "status", "--porcelain", "-z")
+	return len(out) > 0, err
+}
+
+// runGit runs git in dir with stdin as input and returns its output
+func runGit(ctx context.Context, dir, stdin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() != 1 {
+			return out, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+func splitNul(out []byte) []string {
+	var parts []string
+	for _, p := range bytes.Split(out, []byte{0}) {
+		if len(p) > 0 {
+			parts = append(parts, string(p))
+		}
+	}
+	return parts
+}
+
+// hasPathPrefix reports whether any of paths is dir or lies below it, all
+// slash-separated
+func hasPathPrefix(paths []string, dir string) bool {
+	for _, p := range paths {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
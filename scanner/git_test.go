@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// git runs git in dir, with an identity for committing
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %q: %v: %s", args, err, out)
+	}
+}
+
+func TestFindArtifactsInGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	f := newFixture(t)
+	src := filepath.Join(f.home, "src")
+	repo := filepath.Join(src, "repo")
+	writeFile(t, filepath.Join(repo, "package.json"), 2)
+	writeFile(t, filepath.Join(repo, "Cargo.toml"), 2)
+	writeFile(t, filepath.Join(repo, "node_modules", "x"), 10)
+	writeFile(t, filepath.Join(repo, "target", "keep"), 10)
+	writeBytes(t, filepath.Join(repo, ".gitignore"), []byte("/node_modules\n/target\n"))
+	git(t, repo, "init", "-q")
+	git(t, repo, "add", ".")
+	git(t, repo, "add", "-f", "target/keep")
+	git(t, repo, "commit", "-q", "-m", "init")
+
+	s := f.scanner()
+	s.Projects = []string{src}
+	find := func() map[string]Artifact {
+		t.Helper()
+		artifacts, err := s.FindArtifacts(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := make(map[string]Artifact)
+		for _, a := range artifacts {
+			if a.Repo != repo {
+				t.Errorf("%s in repo %q, want %s", a.Path, a.Repo, repo)
+			}
+			byName[a.Name] = a
+		}
+		return byName
+	}
+
+	found := find()
+	if a := found["node_modules"]; a.Blocked != nil || a.Excluded || a.Dirty {
+		t.Errorf("ignored node_modules: blocked %v, excluded %v, dirty %v; want cleanable in a clean repo", a.Blocked, a.Excluded, a.Dirty)
+	}
+	if a := found["target"]; !errors.Is(a.Blocked, errTracked) || !a.Excluded {
+		t.Errorf("tracked target: blocked %v, excluded %v; want blocked as tracked", a.Blocked, a.Excluded)
+	}
+
+	// An artifact git doesn't ignore is untracked work, and makes the repo dirty
+	writeFile(t, filepath.Join(repo, "__pycache__", "m.pyc"), 10)
+	found = find()
+	if a := found["__pycache__"]; !errors.Is(a.Blocked, errNotIgnored) || !a.Excluded {
+		t.Errorf("unignored __pycache__: blocked %v, excluded %v; want blocked as not ignored", a.Blocked, a.Excluded)
+	}
+	for name, a := range found {
+		if !a.Dirty {
+			t.Errorf("%s not marked dirty", name)
+		}
+	}
+}
+
+func TestGitDirty(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, "a"), 10)
+	git(t, repo, "init", "-q")
+	git(t, repo, "add", "a")
+	git(t, repo, "commit", "-q", "-m", "init")
+	ctx := context.Background()
+
+	if dirty, err := gitDirty(ctx, repo); dirty || err != nil {
+		t.Errorf("committed repo: dirty %v, err %v; want clean", dirty, err)
+	}
+	writeFile(t, filepath.Join(repo, "a"), 20)
+	if dirty, err := gitDirty(ctx, repo); !dirty || err != nil {
+		t.Errorf("modified repo: dirty %v, err %v; want dirty", dirty, err)
+	}
+	if _, err := gitDirty(ctx, t.TempDir()); err == nil {
+		t.Error("gitDirty outside a repo succeeded")
+	}
+}
@@ -0,0 +1,111 @@
+package scanner
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LargestFiles is how many of the largest files Scan reports
+const LargestFiles = 100
+
+// largeFileMin is the smallest file worth remembering as one of the largest,
+// which keeps the scan cache from recording every file
+const largeFileMin = 1 << 20
+
+// fileHeap is a min-heap of files by size, so the smallest of the largest is
+// the one to drop
+type fileHeap []*CacheEntry
+
+func (h fileHeap) Len() int { return len(h) }
+func (h fileHeap) Less(i, j int) bool {
+	if h[i].Size != h[j].Size {
+		return h[i].Size < h[j].Size
+	}
+	return h[i].Path > h[j].Path
+}
+func (h fileHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *fileHeap) Push(x any)   { *h = append(*h, x.(*CacheEntry)) }
+func (h *fileHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// largestTracker keeps the LargestFiles largest files seen during a scan.
+// Files with other hard links are left out: deleting one path to them
+// frees nothing.
+type largestTracker struct {
+	mu      sync.Mutex
+	files   fileHeap
+	byPath  map[string]*CacheEntry // The same files, to spot ones found by nested targets twice
+	targets map[*CacheEntry]string // Target path each file was found under
+	now     time.Time              // Reference point for file ages
+}
+
+func newLargestTracker(now time.Time) *largestTracker {
+	return &largestTracker{
+		byPath:  make(map[string]*CacheEntry),
+		targets: make(map[*CacheEntry]string),
+		now:     now,
+	}
+}
+
+// offer considers the file at path, found inside target
+func (t *largestTracker) offer(path string, f cachedFile, target *CacheEntry) {
+	if f.Size < largeFileMin {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.byPath[path]; ok {
+		// Nested targets: the file belongs to the innermost one
+		if len(target.Path) > len(t.targets[e]) {
+			e.Description, e.Category, e.Risk = target.Description, target.Category, target.Risk
+			t.targets[e] = target.Path
+		}
+		return
+	}
+	if len(t.files) == LargestFiles && f.Size <= t.files[0].Size {
+		return
+	}
+	mod := time.Unix(0, f.ModTime)
+	e := &CacheEntry{
+		Name:        f.Name,
+		Path:        path,
+		Size:        f.Size,
+		AllocSize:   f.Alloc,
+		FileCount:   1,
+		LastMod:     mod,
+		OldestMod:   mod,
+		Description: target.Description,
+		Category:    target.Category,
+		Risk:        target.Risk,
+	}
+	e.Ages.Add(mod, t.now, f.Size)
+	heap.Push(&t.files, e)
+	t.byPath[path] = e
+	t.targets[e] = target.Path
+	if len(t.files) > LargestFiles {
+		dropped := heap.Pop(&t.files).(*CacheEntry)
+		delete(t.byPath, dropped.Path)
+		delete(t.targets, dropped)
+	}
+}
+
+// sorted returns the files kept, largest first
+func (t *largestTracker) sorted() []*CacheEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	files := make([]*CacheEntry, len(t.files))
+	copy(files, t.files)
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// sparseFile creates a file of size bytes without writing them
+func sparseFile(t *testing.T, path string, size int64) {
+	t.Helper()
+	writeFile(t, path, 0)
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanListsLargestFiles(t *testing.T) {
+	f := newFixture(t)
+	sparseFile(t, filepath.Join(f.root, "mid"), 2*largeFileMin)
+	sparseFile(t, filepath.Join(f.root, "sub", "big"), 5*largeFileMin)
+	sparseFile(t, filepath.Join(f.root, "min"), largeFileMin)
+	writeFile(t, filepath.Join(f.root, "small"), 1000)
+
+	result, err := f.scanner().Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range result.Largest {
+		names = append(names, e.Name)
+		if e.Description != "Yarn Cache" {
+			t.Errorf("%s described as %q, want its target's description", e.Name, e.Description)
+		}
+	}
+	if want := []string{"big", "mid", "min"}; !slices.Equal(names, want) {
+		t.Errorf("largest = %q, want %q", names, want)
+	}
+}
+
+func TestLargestTrackerKeepsTopFiles(t *testing.T) {
+	tr := newLargestTracker(time.Now())
+	target := &CacheEntry{Path: "/t", Description: "Outer"}
+	offer := func(i int) {
+		size := int64(largeFileMin + i)
+		tr.offer(fmt.Sprintf("/t/%03d", i), cachedFile{Name: fmt.Sprint(i), Size: size, Alloc: size}, target)
+	}
+	// More files than fit, smallest and largest mixed up
+	for i := range LargestFiles + 20 {
+		offer((i * 37) % (LargestFiles + 20))
+	}
+	tr.offer("/t/tiny", cachedFile{Name: "tiny", Size: largeFileMin - 1}, target)
+
+	files := tr.sorted()
+	if len(files) != LargestFiles {
+		t.Fatalf("kept %d files, want %d", len(files), LargestFiles)
+	}
+	for i, e := range files {
+		// The 20 smallest are gone, the rest largest first
+		if want := int64(largeFileMin + LargestFiles + 19 - i); e.Size != want {
+			t.Fatalf("file %d is %d bytes, want %d", i, e.Size, want)
+		}
+	}
+	if len(tr.byPath) != LargestFiles || len(tr.targets) != LargestFiles {
+		t.Errorf("tracking %d paths and %d targets, want only the kept files", len(tr.byPath), len(tr.targets))
+	}
+
+	// Too small to make it in anymore
+	offer(0)
+	if tr.sorted()[LargestFiles-1].Size != largeFileMin+20 {
+		t.Error("a file smaller than all the kept ones evicted one")
+	}
+
+	// Met again by a nested target, the file belongs to it
+	inner := &CacheEntry{Path: "/t/inner", Description: "Inner"}
+	path := fmt.Sprintf("/t/%03d", LargestFiles+19)
+	tr.offer(path, cachedFile{Name: "x", Size: largeFileMin + LargestFiles + 19}, inner)
+	tr.offer(path, cachedFile{Name: "x", Size: largeFileMin + LargestFiles + 19}, target)
+	if files := tr.sorted(); len(files) != LargestFiles || files[0].Description != "Inner" {
+		t.Errorf("top file described as %q among %d, want Inner and no duplicate", files[0].Description, len(files))
+	}
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileID identifies a file by device and inode
+type fileID struct {
+	dev, ino uint64
+}
+
+// linkedFile is a file with more than one hard link, seen during a scan
+type linkedFile struct {
+	size  int64
+	alloc int64
+	mod   time.Time
+	nlink uint64
+	seen  []linkOccurrence
+}
+
+// linkOccurrence is one path to a linkedFile found during a walk
+type linkOccurrence struct {
+	leaf   *CacheEntry // Entry whose walk found the path
+	target *CacheEntry // Top-level target containing leaf
+}
+
+// linkTracker collects hard-linked files so each inode is counted once
+type linkTracker struct {
+	mu    sync.Mutex
+	files map[fileID]*linkedFile
+	now   time.Time // Reference point for file ages
+}
+
+func newLinkTracker(now time.Time) *linkTracker {
+	return &linkTracker{files: make(map[fileID]*linkedFile), now: now}
+}
+
+// record notes one path to a hard-linked file and reports whether it is the
+// first path to that inode seen in this scan
+func (t *linkTracker) record(l cachedLink, leaf, target *CacheEntry) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := fileID{dev: l.Dev, ino: l.Ino}
+	f, ok := t.files[id]
+	if !ok {
+		f = &linkedFile{size: l.Size, alloc: l.Alloc, mod: time.Unix(0, l.ModTime), nlink: l.Nlink}
+		t.files[id] = f
+	}
+	f.seen = append(f.seen, linkOccurrence{leaf: leaf, target: target})
+	return !ok
+}
+
+// resolve charges each hard-linked file to exactly one entry, the one with
+// the lexicographically first path so the outcome doesn't depend on walk
+// order. Where links outside that entry keep the data alive, the bytes are
+// also added to its SharedSize, since deleting the entry won't free them.
+func (t *linkTracker) resolve() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, f := range t.files {
+		sort.SliceStable(f.seen, func(i, j int) bool {
+			return f.seen[i].leaf.Path < f.seen[j].leaf.Path
+		})
+		owner := f.seen[0]
+
+		var inLeaf, inTarget uint64
+		for _, o := range f.seen {
+			if o.leaf == owner.leaf {
+				inLeaf++
+			}
+			if o.target == owner.target {
+				inTarget++
+			}
+		}
+
+		owner.leaf.Size += f.size
+		owner.leaf.AllocSize += f.alloc
+		owner.leaf.Ages.Add(f.mod, t.now, f.size)
+		if inLeaf < f.nlink {
+			owner.leaf.SharedSize += f.alloc
+		}
+		if owner.target != owner.leaf {
+			owner.target.Size += f.size
+			owner.target.AllocSize += f.alloc
+			owner.target.Ages.Add(f.mod, t.now, f.size)
+			if inTarget < f.nlink {
+				owner.target.SharedSize += f.alloc
+			}
+		}
+	}
+}
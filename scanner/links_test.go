@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func link(t *testing.T, target, path string) {
+	t.Helper()
+	mkdir(t, filepath.Dir(path))
+	if err := os.Link(target, path); err != nil {
+		t.Skipf("hard links unsupported: %v", err)
+	}
+}
+
+func TestHardLinksInOneTarget(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "a", "file"), 10000)
+	link(t, filepath.Join(f.root, "a", "file"), filepath.Join(f.root, "b", "file"))
+	writeFile(t, filepath.Join(f.root, "b", "own"), 100)
+
+	entry := scanEntry(t, f.scanner(), f.root)
+	if entry.Size != 10100 || entry.FileCount != 3 {
+		t.Errorf("root = %d bytes in %d files, want 10100 in 3", entry.Size, entry.FileCount)
+	}
+	if entry.SharedSize != 0 {
+		t.Errorf("root shared = %d, want 0: both links are inside it", entry.SharedSize)
+	}
+
+	// The first path charged; the other child only has its own file
+	a, b := childNamed(t, entry, "a"), childNamed(t, entry, "b")
+	if a.Size != 10000 || a.SharedSize != a.AllocSize || a.SharedSize == 0 {
+		t.Errorf("a = %d bytes, %d of %d on disk shared; want 10000, all shared with b", a.Size, a.SharedSize, a.AllocSize)
+	}
+	if b.Size != 100 || b.SharedSize != 0 {
+		t.Errorf("b = %d bytes, %d shared; want 100, none", b.Size, b.SharedSize)
+	}
+}
+
+func TestHardLinksAcrossTargets(t *testing.T) {
+	f := newFixture(t)
+	gradle := filepath.Join(f.home, ".gradle", "caches")
+	writeFile(t, filepath.Join(f.root, "x", "big"), 20000)
+	link(t, filepath.Join(f.root, "x", "big"), filepath.Join(gradle, "y", "big"))
+	writeFile(t, filepath.Join(gradle, "y", "own"), 300)
+
+	result, err := f.scanner().Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalSize != 20300 {
+		t.Errorf("total = %d, want 20300 with the linked file once", result.TotalSize)
+	}
+
+	// The lexicographically first path owns the inode, whatever the walk order
+	yarn := scanEntry(t, f.scanner(), f.root)
+	if yarn.Size != 20000 || yarn.SharedSize != yarn.AllocSize || yarn.SharedSize == 0 {
+		t.Errorf("yarn = %d bytes, %d of %d on disk shared; want 20000, all shared with gradle",
+			yarn.Size, yarn.SharedSize, yarn.AllocSize)
+	}
+	g := scanEntry(t, f.scanner(), gradle)
+	if g.Size != 300 || g.SharedSize != 0 || g.FileCount != 2 {
+		t.Errorf("gradle = %d bytes in %d files, %d shared; want 300 in 2, none shared", g.Size, g.FileCount, g.SharedSize)
+	}
+}
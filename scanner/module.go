@@ -0,0 +1,202 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/han-nwin/dusty/cleaner"
+)
+
+// Module is a source of targets. The allowlisted paths are the built-in
+// module; others, like Docker or package managers, add theirs with
+// Register. Entries a module returns need not be files, but their paths
+// must be ones the module Owns.
+type Module interface {
+	// Name identifies the module in errors and listings
+	Name() string
+	// Available reports whether the module can run here, such as its tool
+	// being installed
+	Available(ctx context.Context, s *Scanner) bool
+	// Scan returns the module's targets as entry trees, along with the
+	// targets it couldn't scan in Errors
+	Scan(ctx context.Context, s *Scanner) (*ScanResult, error)
+	// Owns reports whether the entry at path came from the module
+	Owns(path string) bool
+	// Clean removes the given entries, selected by the user, and reports
+	// the on-disk bytes freed and any failure for each. One that fails
+	// doesn't stop the rest.
+	Clean(ctx context.Context, s *Scanner, entries []*CacheEntry, opts CleanOptions) *cleaner.Report
+}
+
+// CleanOptions says how to clean
+type CleanOptions struct {
+	Age      AgeFilter        // Only remove what is older than this, when active
+	Strategy cleaner.Strategy // How files are removed, where that makes sense; nil deletes
+}
+
+// strategy returns opts.Strategy, cleaner.Delete by default
+func (o CleanOptions) strategy() cleaner.Strategy {
+	if o.Strategy == nil {
+		return cleaner.Delete
+	}
+	return o.Strategy
+}
+
+// DryRun reports whether nothing should actually be removed
+func (o CleanOptions) DryRun() bool {
+	return o.Strategy == cleaner.DryRun
+}
+
+// AgeMatcher is implemented by modules that can tell what an age cutoff
+// would clean before cleaning
+type AgeMatcher interface {
+	MatchOlderThan(ctx context.Context, s *Scanner, entries []*CacheEntry, f AgeFilter) (AgeMatch, error)
+}
+
+// WholeCleaner is implemented by modules that can only clean a target as a
+// whole, like a package manager's cache. Selecting part of such a target
+// selects all of it.
+type WholeCleaner interface {
+	CleansWhole() bool
+}
+
+// Trasher is implemented by modules whose entries can be moved to the
+// trash. The others can only delete theirs, like Docker pruning through the
+// daemon, and refuse cleaner.Trash rather than delete for good.
+type Trasher interface {
+	CanTrash() bool
+}
+
+// CanTrash reports whether the module owning the entry at path can move it
+// to the trash
+func CanTrash(path string) bool {
+	t, ok := ModuleFor(path).(Trasher)
+	return ok && t.CanTrash()
+}
+
+// ErrNoTrash is the error for each entry of a module that isn't a Trasher
+// when asked to move it to the trash
+var ErrNoTrash = errors.New("can't be moved to the Trash, only cleaned for good")
+
+// RefuseTrash reports every entry as failed with ErrNoTrash, for modules
+// that can't move their entries to the trash
+func RefuseTrash(entries []*CacheEntry) *cleaner.Report {
+	report := &cleaner.Report{}
+	for _, e := range entries {
+		report.Add(cleaner.Result{Path: e.Path, Err: fmt.Errorf("%s: %w", e.Path, ErrNoTrash)})
+	}
+	return report
+}
+
+var (
+	modulesMu sync.Mutex
+	modules   = []Module{pathModule{}}
+)
+
+// Register adds a module after the ones registered so far. It's meant to be
+// called from init functions.
+func Register(m Module) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	modules = append(modules, m)
+}
+
+// Modules returns every module, the allowlisted paths first
+func Modules() []Module {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	return append([]Module(nil), modules...)
+}
+
+// ModuleFor returns the module owning the entry at path, the allowlisted
+// paths if no other does
+func ModuleFor(path string) Module {
+	all := Modules()
+	for _, m := range all[1:] {
+		if m.Owns(path) {
+			return m
+		}
+	}
+	return all[0]
+}
+
+// pathModule sizes and cleans the allowlisted paths from GetAllowedPaths
+type pathModule struct{}
+
+func (pathModule) Name() string { return "paths" }
+
+func (pathModule) Available(context.Context, *Scanner) bool { return true }
+
+// CanTrash is always true: files can always be moved to the trash
+func (pathModule) CanTrash() bool { return true }
+
+func (pathModule) Owns(path string) bool { return filepath.IsAbs(path) }
+
+func (pathModule) Scan(ctx context.Context, s *Scanner) (*ScanResult, error) {
+	return s.scanPaths(ctx)
+}
+
+// Clean removes the entries, around any excluded items inside them, with
+// opts.Strategy. With an age cutoff only old files go and fresh ones stay
+// warm; that always deletes, unless it's a dry run.
+func (pathModule) Clean(ctx context.Context, s *Scanner, entries []*CacheEntry, opts CleanOptions) *cleaner.Report {
+	start := time.Now()
+	report := &cleaner.Report{}
+	var paths []string
+	for _, e := range entries {
+		cleanable, err := s.CleanablePaths(e.Path)
+		if err != nil {
+			report.Add(cleaner.Result{Path: e.Path, Err: fmt.Errorf("failed to clean %s: %v", e.Path, err)})
+			continue
+		}
+		paths = append(paths, cleanable...)
+	}
+
+	if !opts.Age.Active() {
+		c := cleaner.Cleaner{Strategy: opts.strategy(), Check: s.CheckCleanablePath}
+		report.Merge(c.Clean(ctx, paths))
+		report.Duration = time.Since(start)
+		return report
+	}
+	for _, path := range paths {
+		res := cleaner.Result{Path: path}
+		began := time.Now()
+		var matched AgeMatch
+		err := ctx.Err()
+		if err == nil {
+			err = s.CheckCleanablePath(path)
+		}
+		if err == nil {
+			if opts.DryRun() {
+				matched, err = s.MatchOlderThan(path, opts.Age)
+			} else {
+				matched, err = s.RemoveOlderThan(path, opts.Age)
+			}
+			if err != nil {
+				err = fmt.Errorf("failed to clean %s: %v", path, err)
+			}
+		}
+		res.Freed, res.Err, res.Duration = matched.AllocSize, err, time.Since(began)
+		report.Add(res)
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (pathModule) MatchOlderThan(_ context.Context, s *Scanner, entries []*CacheEntry, f AgeFilter) (AgeMatch, error) {
+	var total AgeMatch
+	for _, e := range entries {
+		match, err := s.MatchOlderThan(e.Path, f)
+		total.Files += match.Files
+		total.Size += match.Size
+		total.AllocSize += match.AllocSize
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Built-in profile names
+const (
+	ProfileFull      = "Full"
+	ProfileDeveloper = "Developer"
+	ProfileBrowser   = "Browser"
+)
+
+// Profile selects a subset of targets. A target is in the profile if its
+// category is listed in Categories, or its description or path is listed in
+// Targets. A profile with no selectors at all includes every target.
+type Profile struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`
+	Targets    []string `json:"targets"` // Descriptions, or paths with ~ and $VAR
+}
+
+// builtinProfiles are always available; config profiles with the same name
+// replace them
+var builtinProfiles = []Profile{
+	{Name: ProfileFull},
+	{Name: ProfileDeveloper, Categories: []string{CategoryDeveloper}},
+	{Name: ProfileBrowser, Categories: []string{CategoryBrowser}},
+}
+
+// Profiles returns the built-in profiles followed by the user's own
+func (s *Scanner) Profiles() []Profile {
+	profiles := append([]Profile(nil), builtinProfiles...)
+	if s.Config == nil {
+		return profiles
+	}
+	for _, p := range s.Config.Profiles {
+		replaced := false
+		for i := range profiles {
+			if strings.EqualFold(profiles[i].Name, p.Name) {
+				profiles[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// FindProfile looks up a profile by name, ignoring case. An empty name is
+// the Full profile.
+func (s *Scanner) FindProfile(name string) (Profile, error) {
+	if name == "" {
+		name = ProfileFull
+	}
+	var names []string
+	for _, p := range s.Profiles() {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q (have %s)", name, strings.Join(names, ", "))
+}
+
+// includes reports whether t belongs to the profile. Paths in p.Targets are
+// expected to be expanded already (see LoadConfig).
+func (p Profile) includes(t Target) bool {
+	if len(p.Categories) == 0 && len(p.Targets) == 0 {
+		return true
+	}
+	for _, c := range p.Categories {
+		if strings.EqualFold(c, t.Category) {
+			return true
+		}
+	}
+	for _, sel := range p.Targets {
+		if sel == t.Path || strings.EqualFold(sel, t.Description) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the targets in the profile
+func (p Profile) filter(targets []Target) []Target {
+	var selected []Target
+	for _, t := range targets {
+		if p.includes(t) {
+			selected = append(selected, t)
+		}
+	}
+	return selected
+}
+
+// isPathSelector reports whether a Targets entry names a path rather than a
+// description
+func isPathSelector(sel string) bool {
+	return strings.HasPrefix(sel, "~") || strings.HasPrefix(sel, "$") || filepath.IsAbs(sel)
+}
+
+// validate checks a config profile and expands its path selectors
+func (p *Profile) validate(home string) error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(p.Categories) == 0 && len(p.Targets) == 0 {
+		return errors.New("needs at least one category or target")
+	}
+	for i, sel := range p.Targets {
+		if !isPathSelector(sel) {
+			continue
+		}
+		expanded, err := expandPath(sel, home)
+		if err == nil && !filepath.IsAbs(expanded) {
+			err = errors.New("path must be absolute or start with ~")
+		}
+		if err != nil {
+			return fmt.Errorf("targets[%d] (%q): %w", i, sel, err)
+		}
+		p.Targets[i] = expanded
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIsPathSelector(t *testing.T) {
+	tests := []struct {
+		sel  string
+		want bool
+	}{
+		{"~/work/.mirror", true},
+		{"~", true},
+		{"$XDG_CACHE_HOME/thumbnails", true},
+		{"${HOME}/x", true},
+		{"/var/cache/x", true},
+		{"Yarn Cache", false},
+		{"cache/x", false}, // A description that happens to have a slash
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isPathSelector(tt.sel); got != tt.want {
+			t.Errorf("isPathSelector(%q) = %v, want %v", tt.sel, got, tt.want)
+		}
+	}
+}
+
+func TestProfileValidate(t *testing.T) {
+	t.Setenv("DUSTY_TEST_DIR", "/srv/mirror")
+	t.Setenv("DUSTY_TEST_REL", "relative")
+	t.Setenv("DUSTY_TEST_UNSET", "")
+	home := "/home/tester"
+	tests := []struct {
+		name    string
+		profile Profile
+		targets []string // Targets once validated
+		err     string   // Part of the error, "" for none
+	}{
+		{"categories", Profile{Name: "Work", Categories: []string{"Developer"}}, nil, ""},
+		{"descriptions and paths",
+			Profile{Name: "Work", Targets: []string{"Yarn Cache", "~/work/.mirror/", "$DUSTY_TEST_DIR/cache"}},
+			[]string{"Yarn Cache", "/home/tester/work/.mirror", "/srv/mirror/cache"}, ""},
+		{"no name", Profile{Categories: []string{"Developer"}}, nil, "name is required"},
+		{"blank name", Profile{Name: "  ", Categories: []string{"Developer"}}, nil, "name is required"},
+		{"no selectors", Profile{Name: "Empty"}, nil, "needs at least one category or target"},
+		{"unset variable", Profile{Name: "Work", Targets: []string{"Yarn Cache", "$DUSTY_TEST_UNSET/x"}}, nil,
+			`targets[1] ("$DUSTY_TEST_UNSET/x"): undefined environment variable DUSTY_TEST_UNSET`},
+		{"relative variable", Profile{Name: "Work", Targets: []string{"$DUSTY_TEST_REL/x"}}, nil,
+			"path must be absolute"},
+		{"other user's home", Profile{Name: "Work", Targets: []string{"~other/cache"}}, nil,
+			"path must be absolute"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.profile
+			err := p.validate(home)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("validate = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("validate = %v, want an error with %q", err, tt.err)
+			}
+			if tt.err == "" && !slices.Equal(p.Targets, tt.targets) {
+				t.Errorf("targets = %q, want %q", p.Targets, tt.targets)
+			}
+		})
+	}
+}
+
+func TestLoadConfigProfiles(t *testing.T) {
+	home := "/home/tester"
+	tests := []struct {
+		name string
+		data string
+		err  string // Part of the error, "" for none
+	}{
+		{"valid", `{"profiles": [
+			{"name": "Work", "categories": ["Developer"], "targets": ["~/work/.mirror"]},
+			{"name": "Developer", "targets": ["Yarn Cache"]}
+		]}`, ""},
+		{"unknown key", `{"profiles": [{"name": "Work", "category": ["Developer"]}]}`,
+			`unknown field "category"`},
+		{"wrong type", `{"profiles": [{"name": "Work", "targets": "Yarn Cache"}]}`,
+			"cannot unmarshal"},
+		{"invalid profile", `{"profiles": [{"name": "Work", "categories": ["Developer"]}, {"name": "Empty"}]}`,
+			`profiles[1] ("Empty"): needs at least one category or target`},
+		{"bad selector", `{"profiles": [{"name": "Work", "targets": ["~nobody/x"]}]}`,
+			`profiles[0] ("Work"): targets[0] ("~nobody/x")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadConfig(t, home, tt.data)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("LoadConfig = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("LoadConfig = %v, want an error with %q", err, tt.err)
+			}
+			if tt.err != "" {
+				return
+			}
+			s := &Scanner{HomeDir: home, Config: cfg}
+			p, err := s.FindProfile("developer")
+			if err != nil || !slices.Equal(p.Targets, []string{"Yarn Cache"}) {
+				t.Errorf("Developer = %+v, %v; want the config's profile in place of the built-in one", p, err)
+			}
+			if p, err := s.FindProfile("work"); err != nil || p.Targets[0] != "/home/tester/work/.mirror" {
+				t.Errorf("Work = %+v, %v; want its path expanded", p, err)
+			}
+		})
+	}
+}
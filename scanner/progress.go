@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressInterval is how often a running scan reports progress between
+// target start/finish events
+const ProgressInterval = 100 * time.Millisecond
+
+// Progress is a snapshot of a running scan, passed to Scanner.OnProgress.
+// Done is a copy without children. Hard-linked files are only charged once
+// every target has finished, so its totals can differ from the final result.
+type Progress struct {
+	Current      string      // Path of the target most recently started
+	FilesSeen    int64       // Files counted so far across all targets
+	BytesCounted int64       // Bytes counted so far across all targets
+	TargetsDone  int         // Targets finished, including ones that failed
+	TargetsTotal int         // Targets in this scan
+	Done         *CacheEntry // Target that just finished, nil for periodic updates
+}
+
+// progressTracker accumulates counters from concurrent walkers and
+// serializes calls to the progress callback
+type progressTracker struct {
+	files   atomic.Int64
+	bytes   atomic.Int64
+	done    atomic.Int64
+	current atomic.Value // string
+	total   int
+
+	mu       sync.Mutex // Serializes callback invocations
+	callback func(Progress)
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func newProgressTracker(total int, callback func(Progress)) *progressTracker {
+	p := &progressTracker{
+		total:    total,
+		callback: callback,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	p.current.Store("")
+	if callback != nil {
+		go p.tick()
+	} else {
+		close(p.stopped)
+	}
+	return p
+}
+
+// tick reports periodic snapshots until close is called
+func (p *progressTracker) tick() {
+	defer close(p.stopped)
+	t := time.NewTicker(ProgressInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			p.report(nil)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// close stops periodic reports; no callback runs after it returns
+func (p *progressTracker) close() {
+	close(p.stop)
+	<-p.stopped
+}
+
+// addFile records one file of the given size
+func (p *progressTracker) addFile(size int64) {
+	p.addFiles(1, size)
+}
+
+// addFiles records several files totalling size bytes
+func (p *progressTracker) addFiles(n int, size int64) {
+	p.files.Add(int64(n))
+	p.bytes.Add(size)
+}
+
+// startTarget records that a target began scanning
+func (p *progressTracker) startTarget(path string) {
+	p.current.Store(path)
+	p.report(nil)
+}
+
+// finishTarget records that a target finished; entry is nil on failure
+func (p *progressTracker) finishTarget(entry *CacheEntry) {
+	p.done.Add(1)
+	if entry == nil {
+		p.report(nil)
+		return
+	}
+	// Hand out a copy: the scan keeps updating entry after this
+	snapshot := *entry
+	snapshot.Children = nil
+	p.report(&snapshot)
+}
+
+func (p *progressTracker) report(done *CacheEntry) {
+	if p.callback == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callback(Progress{
+		Current:      p.current.Load().(string),
+		FilesSeen:    p.files.Load(),
+		BytesCounted: p.bytes.Load(),
+		TargetsDone:  int(p.done.Load()),
+		TargetsTotal: p.total,
+		Done:         done,
+	})
+}
@@ -0,0 +1,291 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CategoryProjects is the category of build artifacts found in project roots
+const CategoryProjects = "Projects"
+
+// artifactKind describes one kind of build-artifact directory
+type artifactKind struct {
+	name        string   // Directory name
+	markers     []string // Files next to the directory, one of which must exist
+	contents    []string // Files inside the directory, one of which must exist
+	description string
+	risk        string
+}
+
+var gradleMarkers = []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}
+
+var artifactKinds = []artifactKind{
+	{"node_modules", []string{"package.json"}, nil, "Node modules", "Packages are reinstalled by the next npm, yarn or pnpm install"},
+	{"target", []string{"Cargo.toml"}, nil, "Cargo build output", "The next cargo build recompiles from scratch"},
+	{"build", gradleMarkers, nil, "Gradle build output", "The next Gradle build recompiles from scratch"},
+	{".gradle", gradleMarkers, nil, "Gradle project cache", "The next Gradle build is slower once"},
+	{".venv", nil, []string{"pyvenv.cfg"}, "Python virtualenv", "The virtualenv has to be recreated and its packages reinstalled"},
+	{"__pycache__", nil, nil, "Python bytecode", "Python recompiles modules on the next run"},
+}
+
+// projectMarkers are files that make a directory the root of a project
+var projectMarkers = append([]string{".git", "package.json", "Cargo.toml", "pyproject.toml", "setup.py", "requirements.txt"}, gradleMarkers...)
+
+// Artifact is a build-artifact directory found inside a project. Inside a
+// git working tree it's only cleanable if git ignores it; otherwise it's
+// marked Excluded, with the reason in Blocked.
+type Artifact struct {
+	*CacheEntry
+	Project         string    // Directory of the project owning the artifact
+	ProjectModified time.Time // Latest change in the project outside its artifacts
+	Repo            string    // Git working tree containing the artifact, if any
+	Dirty           bool      // Repo has uncommitted changes
+	Blocked         error     // Why the artifact can't be cleaned, nil if it can
+}
+
+// ProjectRoots returns the directories searched for build artifacts: the
+// config file's projects followed by s.Projects
+func (s *Scanner) ProjectRoots() []string {
+	var roots []string
+	if s.Config != nil {
+		roots = append(roots, s.Config.Projects...)
+	}
+	for _, root := range s.Projects {
+		if expanded, err := expandPath(root, s.HomeDir); err == nil && filepath.IsAbs(expanded) {
+			roots = append(roots, expanded)
+		}
+	}
+	return roots
+}
+
+// artifactKindOf returns the kind of artifact the directory at path is, if
+// it is one
+func artifactKindOf(path string) (artifactKind, bool) {
+	name := filepath.Base(path)
+	for _, k := range artifactKinds {
+		if k.name != name {
+			continue
+		}
+		if len(k.markers) > 0 && !anyExists(filepath.Dir(path), k.markers) {
+			continue
+		}
+		if len(k.contents) > 0 && !anyExists(path, k.contents) {
+			continue
+		}
+		return k, true
+	}
+	return artifactKind{}, false
+}
+
+func anyExists(dir string, names []string) bool {
+	for _, name := range names {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// FindArtifacts walks the project roots for build-artifact directories and
+// sizes each one. Artifacts aren't searched inside, nor are .git
+// directories; excluded paths and, with OneFileSystem, other filesystems are
+// skipped. Artifacts come back largest first.
+func (s *Scanner) FindArtifacts(ctx context.Context) ([]Artifact, error) {
+	var artifacts []Artifact
+	newest := make(map[string]time.Time) // Latest change directly in each walked directory
+	ex := s.exclusions()
+	seen := make(map[string]bool)
+	for _, root := range s.ProjectRoots() {
+		var rootDev fileID
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				return nil // Unreadable or missing; skip it
+			}
+			if _, ok := ex.match(p); ok {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if !d.IsDir() {
+				dir := filepath.Dir(p)
+				if info.ModTime().After(newest[dir]) {
+					newest[dir] = info.ModTime()
+				}
+				return nil
+			}
+
+			if p == root {
+				rootDev, _, _ = fileIdentity(info)
+			} else if s.OneFileSystem && !sameDevice(info, rootDev) {
+				return filepath.SkipDir
+			}
+			if seen[p] {
+				return filepath.SkipDir // Part of another root
+			}
+			seen[p] = true
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if k, ok := artifactKindOf(p); ok && p != root {
+				artifacts = append(artifacts, Artifact{
+					CacheEntry: &CacheEntry{
+						Name:        d.Name(),
+						Path:        p,
+						LastMod:     info.ModTime(),
+						OldestMod:   info.ModTime(),
+						IsDir:       true,
+						Description: k.description,
+						Category:    CategoryProjects,
+						Risk:        k.risk,
+					},
+					Project: projectOf(p, root),
+				})
+				return filepath.SkipDir
+			}
+			if info.ModTime().After(newest[p]) {
+				newest[p] = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	// Roll each directory's latest change up into its parents, deepest first
+	dirs := make([]string, 0, len(newest))
+	for dir := range newest {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i], string(filepath.Separator)) > strings.Count(dirs[j], string(filepath.Separator))
+	})
+	for _, dir := range dirs {
+		parent := filepath.Dir(dir)
+		if t, ok := newest[parent]; ok && newest[dir].After(t) {
+			newest[parent] = newest[dir]
+		}
+	}
+
+	s.checkArtifactsInGit(ctx, artifacts)
+
+	st := s.newScanState(ctx, len(artifacts))
+	var wg sync.WaitGroup
+	for _, a := range artifacts {
+		wg.Go(func() { s.scanSize(st, a.CacheEntry, a.CacheEntry) })
+	}
+	wg.Wait()
+	st.progress.close()
+	st.links.resolve()
+
+	for i := range artifacts {
+		artifacts[i].ProjectModified = newest[artifacts[i].Project]
+	}
+	sort.SliceStable(artifacts, func(i, j int) bool { return artifacts[i].Size > artifacts[j].Size })
+	return artifacts, ctx.Err()
+}
+
+// checkArtifactsInGit asks git about the artifacts inside working trees,
+// one call per repository, and blocks every one git doesn't ignore
+func (s *Scanner) checkArtifactsInGit(ctx context.Context, artifacts []Artifact) {
+	byRepo := make(map[string][]*Artifact)
+	var repos []string
+	for i := range artifacts {
+		a := &artifacts[i]
+		a.Repo = gitRepoOf(a.Project)
+		if a.Repo == "" {
+			continue
+		}
+		if byRepo[a.Repo] == nil {
+			repos = append(repos, a.Repo)
+		}
+		byRepo[a.Repo] = append(byRepo[a.Repo], a)
+	}
+
+	for _, repo := range repos {
+		in := byRepo[repo]
+		dirs := make([]string, len(in))
+		for i, a := range in {
+			dirs[i] = a.Path
+		}
+		failed, err := gitCheckIgnored(ctx, repo, dirs)
+		dirty, dirtyErr := gitDirty(ctx, repo)
+		for _, a := range in {
+			a.Dirty = dirty && dirtyErr == nil
+			switch {
+			case err != nil:
+				a.Blocked = fmt.Errorf("can't check whether git ignores it: %w", err)
+			case failed[a.Path] != nil:
+				a.Blocked = failed[a.Path]
+			}
+			if a.Blocked != nil {
+				a.Excluded = true
+			}
+		}
+	}
+}
+
+// projectOf returns the project owning the artifact at path: the closest
+// directory above it with a project marker, or its parent if there's none
+// up to root
+func projectOf(path, root string) string {
+	for dir := filepath.Dir(path); Within(dir, root); dir = filepath.Dir(dir) {
+		if anyExists(dir, projectMarkers) {
+			return dir
+		}
+		if dir == root {
+			break
+		}
+	}
+	return filepath.Dir(path)
+}
+
+// checkArtifactPath allows cleaning path if it is, or lies inside, a build
+// artifact below one of the project roots. The artifact is checked on disk
+// again, and with git if it's in a working tree, so nothing else in a
+// project can be cleaned.
+func (s *Scanner) checkArtifactPath(path string) (bool, error) {
+	for _, root := range s.ProjectRoots() {
+		if !Within(path, root) || path == root {
+			continue
+		}
+		for dir := path; dir != root; dir = filepath.Dir(dir) {
+			info, err := os.Lstat(dir)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			if _, ok := artifactKindOf(dir); !ok {
+				continue
+			}
+			if err := s.checkUnderRoot(path, root); err != nil {
+				return true, err
+			}
+			if repo := gitRepoOf(dir); repo != "" {
+				failed, err := gitCheckIgnored(context.Background(), repo, []string{dir})
+				if err == nil {
+					err = failed[dir]
+				}
+				if err != nil {
+					return true, fmt.Errorf("refusing to clean %s: %w", path, err)
+				}
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func touch(t *testing.T, path string, mod time.Time) {
+	t.Helper()
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindArtifacts(t *testing.T) {
+	f := newFixture(t)
+	src := filepath.Join(f.home, "src")
+	now := time.Now()
+
+	writeFile(t, filepath.Join(src, "web", "package.json"), 2)
+	writeFile(t, filepath.Join(src, "web", "node_modules", "a", "index.js"), 3000)
+	// Never searched inside an artifact
+	writeFile(t, filepath.Join(src, "web", "node_modules", "a", "package.json"), 2)
+	writeFile(t, filepath.Join(src, "web", "node_modules", "a", "node_modules", "b"), 200)
+	writeFile(t, filepath.Join(src, "web", "lib", "main.js"), 10)
+	touch(t, filepath.Join(src, "web", "lib", "main.js"), now.Add(30*time.Minute))
+	touch(t, filepath.Join(src, "web", "node_modules", "a", "index.js"), now.Add(time.Hour))
+
+	writeFile(t, filepath.Join(src, "tool", "Cargo.toml"), 2)
+	writeFile(t, filepath.Join(src, "tool", "target", "debug", "tool"), 5000)
+
+	writeFile(t, filepath.Join(src, "app", "build.gradle"), 2)
+	writeFile(t, filepath.Join(src, "app", "build", "out.jar"), 1000)
+	writeFile(t, filepath.Join(src, "app", ".gradle", "cache"), 500)
+
+	writeFile(t, filepath.Join(src, "py", "pyproject.toml"), 2)
+	writeFile(t, filepath.Join(src, "py", ".venv", "pyvenv.cfg"), 10)
+	writeFile(t, filepath.Join(src, "py", ".venv", "lib", "x.py"), 2000)
+	writeFile(t, filepath.Join(src, "py", "pkg", "__pycache__", "m.pyc"), 100)
+	writeFile(t, filepath.Join(src, "scripts", "__pycache__", "s.pyc"), 50)
+
+	// Named like artifacts, without what makes them one
+	writeFile(t, filepath.Join(src, "loose", "node_modules", "x"), 10)
+	writeFile(t, filepath.Join(src, "loose", "target", "x"), 10)
+	writeFile(t, filepath.Join(src, "notes", "build", "draft.txt"), 10)
+	writeFile(t, filepath.Join(src, "py", "old", ".venv", "lib", "x.py"), 10)
+
+	s := f.scanner()
+	s.Projects = []string{src}
+	artifacts, err := s.FindArtifacts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		path    string
+		project string
+		size    int64
+	}{
+		{"tool/target", "tool", 5000},
+		{"web/node_modules", "web", 3202},
+		{"py/.venv", "py", 2010},
+		{"app/build", "app", 1000},
+		{"app/.gradle", "app", 500},
+		{"py/pkg/__pycache__", "py", 100},
+		{"scripts/__pycache__", "scripts", 50},
+	}
+	if len(artifacts) != len(want) {
+		var paths []string
+		for _, a := range artifacts {
+			paths = append(paths, a.Path)
+		}
+		t.Fatalf("artifacts = %q, want %d", paths, len(want))
+	}
+	for i, w := range want {
+		a := artifacts[i]
+		if a.Path != filepath.Join(src, w.path) || a.Project != filepath.Join(src, w.project) || a.Size != w.size {
+			t.Errorf("artifact %d = %s of %s, %d bytes; want %s of %s, %d bytes",
+				i, a.Path, a.Project, a.Size, w.path, w.project, w.size)
+		}
+		if a.Category != CategoryProjects || a.Repo != "" || a.Blocked != nil || a.Excluded {
+			t.Errorf("%s: category %q, repo %q, blocked %v", a.Path, a.Category, a.Repo, a.Blocked)
+		}
+	}
+	// The project's latest change doesn't count its artifacts
+	if got, want := artifacts[1].ProjectModified, now.Add(30*time.Minute); !got.Equal(want) {
+		t.Errorf("web modified %v, want %v", got, want)
+	}
+}
+
+func TestProjectOf(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "mono", "package.json"), 2)
+	writeFile(t, filepath.Join(root, "mono", "pkgs", "ui", "package.json"), 2)
+	mkdir(t, filepath.Join(root, "mono", "pkgs", "lib", "src"))
+	mkdir(t, filepath.Join(root, "bare", "deep"))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"mono/node_modules", "mono"},
+		{"mono/pkgs/ui/node_modules", "mono/pkgs/ui"},
+		{"mono/pkgs/lib/src/__pycache__", "mono"},
+		{"bare/deep/__pycache__", "bare/deep"},
+	}
+	for _, tt := range tests {
+		if got := projectOf(filepath.Join(root, tt.path), root); got != filepath.Join(root, tt.want) {
+			t.Errorf("projectOf(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	// Markers above the root don't count
+	if got := projectOf(filepath.Join(root, "mono", "x", "__pycache__"), filepath.Join(root, "mono", "x")); got != filepath.Join(root, "mono", "x") {
+		t.Errorf("projectOf below a root = %s, want its parent", got)
+	}
+}
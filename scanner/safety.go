@@ -0,0 +1,138 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errMountFound stops a walk as soon as a mount point is found
+var errMountFound = errors.New("mount point found")
+
+func isSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
+// markSymlink flags entry as a symlink and records where it points
+func markSymlink(entry *CacheEntry) {
+	entry.IsSymlink = true
+	entry.LinkTarget, _ = os.Readlink(entry.Path)
+}
+
+// sameDevice reports whether info lives on the device in root. If devices
+// can't be compared on this platform, everything counts as the same device.
+func sameDevice(info os.FileInfo, root fileID) bool {
+	id, _, ok := fileIdentity(info)
+	return !ok || id.dev == root.dev
+}
+
+// Within reports whether path is root or lies below it
+func Within(path, root string) bool {
+	if path == root {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// CheckCleanPath returns an error unless path is safe to clean. It must lie
+// inside an allowlisted root or a build artifact in a project root, and the
+// directories leading to it must not be symlinks that point outside that
+// root. A symlink at path itself is fine: removing it only removes the link.
+// With OneFileSystem set, path must also not be or contain a mount point.
+// Excluded paths, and paths containing them, are refused too; see
+// CleanablePaths.
+func (s *Scanner) CheckCleanPath(path string) error {
+	return s.checkCleanPath(path, true)
+}
+
+// CheckCleanablePath is CheckCleanPath for a path CleanablePaths returned:
+// it doesn't walk path again looking for excluded items inside
+func (s *Scanner) CheckCleanablePath(path string) error {
+	return s.checkCleanPath(path, false)
+}
+
+func (s *Scanner) checkCleanPath(path string, walk bool) error {
+	path = filepath.Clean(path)
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("refusing to clean %s: not an absolute path", path)
+	}
+	if r, ok := s.excludedBy(path); ok {
+		return fmt.Errorf("refusing to clean %s: excluded by %q", path, r.pattern)
+	}
+	if walk && s.containsExcluded(path) {
+		return fmt.Errorf("refusing to clean %s: it contains excluded items", path)
+	}
+
+	var lastErr error
+	for _, target := range s.GetAllowedPaths() {
+		root := filepath.Clean(target.Path)
+		if !Within(path, root) {
+			continue
+		}
+		if err := s.checkUnderRoot(path, root); err != nil {
+			// A nested root may still allow it
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	if ok, err := s.checkArtifactPath(path); ok {
+		return err
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	return fmt.Errorf("refusing to clean %s: outside the allowlisted paths", path)
+}
+
+// checkUnderRoot checks path against a single root that lexically contains it
+func (s *Scanner) checkUnderRoot(path, root string) error {
+	if path != root {
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return fmt.Errorf("refusing to clean %s: %w", path, err)
+		}
+		realParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("refusing to clean %s: %w", path, err)
+		}
+		if !Within(realParent, realRoot) {
+			return fmt.Errorf("refusing to clean %s: resolves outside %s", path, root)
+		}
+	}
+
+	if !s.OneFileSystem {
+		return nil
+	}
+	rootInfo, err := os.Lstat(root)
+	if err != nil {
+		return fmt.Errorf("refusing to clean %s: %w", path, err)
+	}
+	rootDev, _, _ := fileIdentity(rootInfo)
+	if crossesDevice(path, rootDev) {
+		return fmt.Errorf("refusing to clean %s: contains another filesystem", path)
+	}
+	return nil
+}
+
+// crossesDevice reports whether path or anything below it sits on a
+// different device than root
+func crossesDevice(path string, root fileID) bool {
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if !sameDevice(info, root) {
+			return errMountFound
+		}
+		return nil
+	})
+	return errors.Is(err, errMountFound)
+}
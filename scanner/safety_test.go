@@ -0,0 +1,249 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixture is a fake home directory with the yarn cache as scan target
+type fixture struct {
+	home    string
+	root    string // ~/.cache/yarn
+	outside string // A directory outside every allowlisted root
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	// Keep the Linux catalog's XDG targets under the fake home
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+
+	dir := t.TempDir()
+	f := &fixture{
+		home:    filepath.Join(dir, "home"),
+		outside: filepath.Join(dir, "outside"),
+	}
+	f.root = filepath.Join(f.home, ".cache", "yarn")
+	mkdir(t, f.root)
+	mkdir(t, f.outside)
+	return f
+}
+
+// scanner scans the Developer profile, where the yarn cache is a target of
+// its own rather than part of ~/.cache
+func (f *fixture) scanner() *Scanner {
+	return &Scanner{HomeDir: f.home, Workers: 2, Profile: ProfileDeveloper}
+}
+
+func mkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	mkdir(t, filepath.Dir(path))
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+}
+
+func scanEntry(t *testing.T, s *Scanner, path string) *CacheEntry {
+	t.Helper()
+	result, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range result.Entries {
+		if e.Path == path {
+			return e
+		}
+	}
+	t.Fatalf("no entry for %s", path)
+	return nil
+}
+
+func childNamed(t *testing.T, e *CacheEntry, name string) *CacheEntry {
+	t.Helper()
+	for _, c := range e.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("%s has no child %s", e.Path, name)
+	return nil
+}
+
+func TestScanReportsSymlinkedChildAsLink(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "real", "a"), 1000)
+	writeFile(t, filepath.Join(f.outside, "big"), 50000)
+	symlink(t, f.outside, filepath.Join(f.root, "link"))
+
+	entry := scanEntry(t, f.scanner(), f.root)
+	link := childNamed(t, entry, "link")
+	if !link.IsSymlink || link.LinkTarget != f.outside {
+		t.Errorf("link: IsSymlink=%v LinkTarget=%q, want true %q", link.IsSymlink, link.LinkTarget, f.outside)
+	}
+	if link.Size >= 50000 {
+		t.Errorf("link size %d includes its target", link.Size)
+	}
+	if entry.Size >= 50000 {
+		t.Errorf("root size %d includes the symlink target", entry.Size)
+	}
+}
+
+func TestScanDoesNotFollowNestedSymlinks(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "pkg", "a"), 1000)
+	writeFile(t, filepath.Join(f.root, "other", "b"), 10)
+	writeFile(t, filepath.Join(f.outside, "big"), 50000)
+	symlink(t, f.outside, filepath.Join(f.root, "pkg", "dir-link"))
+	symlink(t, filepath.Join(f.outside, "big"), filepath.Join(f.root, "pkg", "file-link"))
+
+	pkg := childNamed(t, scanEntry(t, f.scanner(), f.root), "pkg")
+	if pkg.Size >= 50000 {
+		t.Errorf("pkg size %d includes symlink targets", pkg.Size)
+	}
+	if pkg.FileCount != 3 {
+		t.Errorf("pkg file count = %d, want 3 (one file, two links)", pkg.FileCount)
+	}
+}
+
+func TestScanReportsSymlinkedRootAsLink(t *testing.T) {
+	f := newFixture(t)
+	if err := os.Remove(f.root); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(f.outside, "big"), 50000)
+	symlink(t, f.outside, f.root)
+
+	entry := scanEntry(t, f.scanner(), f.root)
+	if !entry.IsSymlink {
+		t.Error("root not reported as symlink")
+	}
+	if len(entry.Children) != 0 || entry.Size >= 50000 {
+		t.Errorf("scan descended into symlinked root: %d children, %d bytes", len(entry.Children), entry.Size)
+	}
+}
+
+func TestOneFileSystemKeepsSameDeviceTree(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "a", "x"), 1000)
+	writeFile(t, filepath.Join(f.root, "b", "deep", "y"), 2000)
+
+	s := f.scanner()
+	s.OneFileSystem = true
+	entry := scanEntry(t, s, f.root)
+	if entry.Size != 3000 || entry.FileCount != 2 {
+		t.Errorf("got %d bytes in %d files, want 3000 in 2", entry.Size, entry.FileCount)
+	}
+	if err := s.CheckCleanPath(filepath.Join(f.root, "b")); err != nil {
+		t.Errorf("CheckCleanPath on same device: %v", err)
+	}
+}
+
+func TestCheckCleanPath(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "pkg", "a"), 10)
+	writeFile(t, filepath.Join(f.outside, "precious"), 10)
+	symlink(t, f.outside, filepath.Join(f.root, "link"))
+
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"child", filepath.Join(f.root, "pkg"), true},
+		{"nested file", filepath.Join(f.root, "pkg", "a"), true},
+		{"root", f.root, true},
+		{"symlink itself", filepath.Join(f.root, "link"), true},
+		{"through symlink", filepath.Join(f.root, "link", "precious"), false},
+		{"outside", filepath.Join(f.outside, "precious"), false},
+		{"dot-dot escape", f.root + "/../../../outside", false},
+		{"home itself", f.home, false},
+		{"relative", "pkg", false},
+	}
+	s := f.scanner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CheckCleanPath(tt.path)
+			if tt.ok && err != nil {
+				t.Errorf("CheckCleanPath(%s) = %v, want nil", tt.path, err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("CheckCleanPath(%s) = nil, want error", tt.path)
+			}
+		})
+	}
+}
+
+func TestCheckCleanPathArtifacts(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	f := newFixture(t)
+	src := filepath.Join(f.home, "src")
+	writeFile(t, filepath.Join(src, "web", "package.json"), 2)
+	writeFile(t, filepath.Join(src, "web", "node_modules", "x", "index.js"), 10)
+	writeFile(t, filepath.Join(src, "tool", "Cargo.toml"), 2)
+	writeFile(t, filepath.Join(src, "tool", "target", "debug", "tool"), 10)
+	writeFile(t, filepath.Join(src, "tool", ".gitignore"), 0)
+	writeFile(t, filepath.Join(src, "notes", "build", "draft.txt"), 10)
+	cmd := exec.Command("git", "init", "-q", filepath.Join(src, "tool"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"artifact", filepath.Join(src, "web", "node_modules"), true},
+		{"inside artifact", filepath.Join(src, "web", "node_modules", "x"), true},
+		{"project", filepath.Join(src, "web"), false},
+		{"project file", filepath.Join(src, "web", "package.json"), false},
+		{"no marker", filepath.Join(src, "notes", "build"), false},
+		{"not ignored by git", filepath.Join(src, "tool", "target"), false},
+	}
+	s := f.scanner()
+	s.Projects = []string{src}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CheckCleanPath(tt.path)
+			if tt.ok && err != nil {
+				t.Errorf("CheckCleanPath(%s) = %v, want nil", tt.path, err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("CheckCleanPath(%s) = nil, want error", tt.path)
+			}
+		})
+	}
+
+	// Once git ignores it, the artifact can go
+	if err := os.WriteFile(filepath.Join(src, "tool", ".gitignore"), []byte("/target\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckCleanPath(filepath.Join(src, "tool", "target")); err != nil {
+		t.Errorf("CheckCleanPath(ignored target) = %v, want nil", err)
+	}
+}
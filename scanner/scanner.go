@@ -1,106 +1,367 @@
 package scanner
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"sync"
 	"time"
 )
 
 // CacheEntry represents a scanned directory or file
 type CacheEntry struct {
-	Name        string
-	Path        string
-	Size        int64
-	FileCount   int
-	LastMod     time.Time
-	OldestMod   time.Time
-	Selected    bool
-	Description string
-	IsParent    bool          // True if this is a parent category
-	Children    []*CacheEntry // Sub-items within this category
-	Expanded    bool          // Whether children are visible
-	Depth       int           // Nesting level for display
+	Name         string
+	Path         string
+	Size         int64 // Apparent size: sum of file lengths
+	AllocSize    int64 // On-disk size: sum of allocated blocks
+	SharedSize   int64 // On-disk bytes hard-linked from outside this entry, not freed by deleting it
+	FileCount    int
+	LastMod      time.Time
+	OldestMod    time.Time
+	Ages         AgeHistogram // Bytes and files by modification age
+	Selected     bool
+	Description  string
+	Category     string
+	Risk         string        // What cleaning this target costs you
+	IsParent     bool          // True if this is a parent category
+	IsDir        bool          // A real directory (not a symlink) that can be listed with ScanChildren
+	Children     []*CacheEntry // Sub-items within this category
+	Listed       bool          // Children holds every non-empty item inside the entry
+	Pending      bool          // Placeholder from ListTargets, not sized yet
+	Expanded     bool          // Whether children are visible
+	Depth        int           // Nesting level for display
+	Excluded     bool          // Matches an exclude rule: shown, but not counted or cleaned
+	ExcludedSize int64         // Bytes inside this entry skipped by exclude rules
+	IsSymlink    bool          // Entry is a symlink; it is never followed
+	LinkTarget   string        // Where the symlink points, for display only
+	Covers       string        // Directory a module's entry accounts for; path targets at or below it are left out, and the entry is left out below a path target
 }
 
 // ScanResult holds all scan results
 type ScanResult struct {
-	Entries   []*CacheEntry
-	TotalSize int64
-	ScanTime  time.Duration
+	Entries        []*CacheEntry
+	TotalSize      int64
+	TotalAllocSize int64
+	ScanTime       time.Duration
+	Errors         []error       // Targets that exist but could not be scanned
+	Largest        []*CacheEntry // The LargestFiles largest files across all targets, largest first
 }
 
 // Scanner handles directory scanning
 type Scanner struct {
 	HomeDir string
+	Workers int // Max directories sized concurrently (<= 0 means runtime.NumCPU)
+
+	// OneFileSystem keeps scanning and cleaning on the device of each
+	// allowlisted root, skipping anything mounted below it
+	OneFileSystem bool
+
+	// Config holds the user's target definitions; nil means the built-in
+	// catalog only
+	Config *Config
+
+	// Profile names the profile whose targets are scanned; empty means Full
+	Profile string
+
+	// Cache, if set, holds directory totals from earlier scans; Scan reuses
+	// the ones still valid and saves the cache when done
+	Cache *ScanCache
+
+	// Projects are directories searched for build artifacts along with the
+	// config file's projects; see FindArtifacts
+	Projects []string
+
+	// Shallow reports each target without its children, which
+	// ScanChildren can fill in later on demand. They're still sized in
+	// parallel, so a large target doesn't take a single worker.
+	Shallow bool
+
+	excludeOnce sync.Once
+	excluded    *exclusions
+
+	// OnProgress, if set, receives progress snapshots while Scan runs.
+	// Calls are serialized and never happen after Scan returns.
+	OnProgress func(Progress)
+}
+
+// scanState holds the shared state of a single Scan call
+type scanState struct {
+	ctx      context.Context
+	sem      chan struct{} // Bounds the number of concurrent size walks
+	progress *progressTracker
+	links    *linkTracker
+	largest  *largestTracker
+	excludes *exclusions
+	targets  map[string]Target // Every target by path, for labelling nested ones
+	cache    *ScanCache        // Nil to read everything
+	now      time.Time         // Reference point for file ages
 }
 
-// NewScanner creates a new scanner
+// NewScanner creates a new scanner with the user's config file loaded. An
+// invalid config file is an error.
 func NewScanner() (*Scanner, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
-	return &Scanner{HomeDir: home}, nil
-}
-
-// GetAllowedPaths returns the list of allowed paths to scan
-func (s *Scanner) GetAllowedPaths() []struct {
-	Path        string
-	Description string
-} {
-	return []struct {
-		Path        string
-		Description string
-	}{
-		{filepath.Join(s.HomeDir, "Library", "Caches"), "System & App Caches"},
-		{filepath.Join(s.HomeDir, "Library", "Logs"), "Log Files"},
-		{filepath.Join(s.HomeDir, "Library", "Developer", "Xcode", "DerivedData"), "Xcode Build Data"},
-		{filepath.Join(s.HomeDir, "Library", "Developer", "Xcode", "Archives"), "Xcode Archives"},
-		{filepath.Join(s.HomeDir, ".npm", "_cacache"), "npm Cache"},
-		{filepath.Join(s.HomeDir, ".cache", "yarn"), "Yarn Cache"},
-		{filepath.Join(s.HomeDir, "Library", "Caches", "pip"), "Python pip Cache"},
-		{filepath.Join(s.HomeDir, "Library", "Caches", "Homebrew"), "Homebrew Cache"},
-		{filepath.Join(s.HomeDir, ".gradle", "caches"), "Gradle Cache"},
-		{filepath.Join(s.HomeDir, ".cargo", "registry"), "Cargo Registry"},
-		{filepath.Join(s.HomeDir, "Library", "Caches", "Google", "Chrome"), "Chrome Cache"},
-		{filepath.Join(s.HomeDir, "Library", "Caches", "com.apple.Safari"), "Safari Cache"},
-	}
-}
-
-// Scan performs the scan of all allowed paths
-func (s *Scanner) Scan() (*ScanResult, error) {
+	s := &Scanner{HomeDir: home, Workers: runtime.NumCPU()}
+	s.Config, err = LoadConfig(filepath.Join(s.ConfigDir(), ConfigFile), home)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Scan runs every available module in parallel and gathers their targets
+// that belong to s.Profile. A module that fails is reported in Errors and
+// the others still count.
+//
+// If ctx is cancelled, Scan stops walking and returns what it counted so far
+// together with ctx.Err().
+func (s *Scanner) Scan(ctx context.Context) (*ScanResult, error) {
 	start := time.Now()
-	var entries []*CacheEntry
-	var totalSize int64
+	p, err := s.FindProfile(s.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	mods := Modules()
+	results := make([]*ScanResult, len(mods))
+	errs := make([]error, len(mods))
+	var wg sync.WaitGroup
+	for i, m := range mods {
+		wg.Go(func() {
+			if !m.Available(ctx, s) {
+				return
+			}
+			results[i], errs[i] = m.Scan(ctx, s)
+		})
+	}
+	wg.Wait()
+
+	var all []*CacheEntry
+	result := &ScanResult{}
+	for i, r := range results {
+		if errs[i] != nil && !errors.Is(errs[i], context.Canceled) {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", mods[i].Name(), errs[i]))
+		}
+		if r == nil {
+			continue
+		}
+		all = append(all, r.Entries...)
+		result.Errors = append(result.Errors, r.Errors...)
+		result.Largest = append(result.Largest, r.Largest...)
+	}
 
-	for _, target := range s.GetAllowedPaths() {
-		entry, err := s.scanPathWithChildren(target.Path, target.Description)
+	var kept []*CacheEntry
+	for _, e := range all {
+		if e.Size > 0 && p.includes(Target{Path: e.Path, Description: e.Description, Category: e.Category}) {
+			kept = append(kept, e)
+		}
+	}
+	for _, e := range kept {
+		if covered(kept, e) {
+			continue
+		}
+		result.Entries = append(result.Entries, e)
+		result.TotalSize += e.Size
+		result.TotalAllocSize += e.AllocSize
+	}
+	sortBySize(result.Entries)
+	result.ScanTime = time.Since(start)
+	return result, ctx.Err()
+}
+
+// covered reports whether another entry accounts for e: a module's entry
+// covering e's path, or, for a module's entry, a path target that encloses
+// the directory it covers and already counts its bytes
+func covered(entries []*CacheEntry, e *CacheEntry) bool {
+	for _, other := range entries {
+		if other == e {
+			continue
+		}
+		if other.Covers != "" && Within(e.Path, other.Covers) {
+			return true
+		}
+		if e.Covers != "" && other.Covers == "" && Within(e.Covers, other.Path) && !Within(other.Path, e.Covers) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanPaths scans the allowed paths. Targets and their children are sized
+// in parallel, bounded by s.Workers.
+func (s *Scanner) scanPaths(ctx context.Context) (*ScanResult, error) {
+	start := time.Now()
+	targets := outermost(s.GetAllowedPaths())
+	st := s.newScanState(ctx, len(targets))
+
+	// Each result lands in its target's slot so the outcome doesn't depend on
+	// which goroutine finishes first
+	scanned := make([]*CacheEntry, len(targets))
+	complete := make([]bool, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Go(func() {
+			if ctx.Err() != nil {
+				st.progress.finishTarget(nil)
+				return
+			}
+			st.progress.startTarget(target.Path)
+			entry, err := s.scanPathWithChildren(st, target)
+			if err != nil {
+				// Targets that aren't installed are skipped quietly
+				if errors.Is(err, fs.ErrNotExist) {
+					complete[i] = true
+				} else {
+					errs[i] = err
+				}
+				st.progress.finishTarget(nil)
+				return
+			}
+			scanned[i] = entry
+			complete[i] = ctx.Err() == nil
+			st.progress.finishTarget(entry)
+		})
+	}
+	wg.Wait()
+	st.progress.close()
+
+	// Every target is walked, so each hard-linked inode can now be charged once
+	st.links.resolve()
+
+	var scanErrs []error
+	for _, err := range errs {
 		if err != nil {
-			continue // Skip paths that don't exist or can't be read
+			scanErrs = append(scanErrs, err)
+		}
+	}
+
+	if s.Cache != nil {
+		// Only fully walked targets say which old records are gone
+		var roots []string
+		for i, target := range targets {
+			if complete[i] {
+				roots = append(roots, target.Path)
+			}
 		}
+		if err := s.Cache.save(roots); err != nil {
+			scanErrs = append(scanErrs, fmt.Errorf("saving scan cache: %w", err))
+		}
+	}
+
+	var entries []*CacheEntry
+	var totalSize, totalAlloc int64
+	for _, entry := range scanned {
+		if entry == nil {
+			continue
+		}
+		pruneChildren(entry)
 		if entry.Size > 0 {
 			entries = append(entries, entry)
 			totalSize += entry.Size
+			totalAlloc += entry.AllocSize
 		}
 	}
 
 	// Sort by size descending by default
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Size > entries[j].Size
-	})
+	sortBySize(entries)
 
 	return &ScanResult{
-		Entries:   entries,
-		TotalSize: totalSize,
-		ScanTime:  time.Since(start),
-	}, nil
+		Entries:        entries,
+		TotalSize:      totalSize,
+		TotalAllocSize: totalAlloc,
+		ScanTime:       time.Since(start),
+		Errors:         scanErrs,
+		Largest:        st.largest.sorted(),
+	}, ctx.Err()
+}
+
+// outermost drops the targets lying inside another target of the list. The
+// outer target's walk already counts their bytes; they show up as its
+// children instead, labelled by sizeChildren.
+func outermost(targets []Target) []Target {
+	var kept []Target
+	for _, t := range targets {
+		nested := false
+		for _, other := range targets {
+			if other.Path != t.Path && Within(t.Path, other.Path) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func (s *Scanner) newScanState(ctx context.Context, targets int) *scanState {
+	now := time.Now()
+	byPath := make(map[string]Target)
+	for _, t := range s.GetAllowedPaths() {
+		byPath[t.Path] = t
+	}
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &scanState{
+		ctx:      ctx,
+		sem:      make(chan struct{}, workers),
+		progress: newProgressTracker(targets, s.OnProgress),
+		links:    newLinkTracker(now),
+		largest:  newLargestTracker(now),
+		excludes: s.exclusions(),
+		targets:  byPath,
+		cache:    s.Cache,
+		now:      now,
+	}
+}
+
+// pruneChildren drops empty children and sorts the rest by size. It runs
+// once hard links are resolved, since that can move bytes between children.
+func pruneChildren(entry *CacheEntry) {
+	var children []*CacheEntry
+	for _, child := range entry.Children {
+		if child.Size > 0 {
+			children = append(children, child)
+		}
+	}
+
+	// Sort children by size
+	sortBySize(children)
+
+	entry.Children = children
+
+	// If no children or only one, don't show as expandable
+	if len(children) <= 1 {
+		entry.IsParent = false
+	}
 }
 
-// scanPathWithChildren scans a path and its immediate children
-func (s *Scanner) scanPathWithChildren(path, description string) (*CacheEntry, error) {
-	info, err := os.Stat(path)
+// sortBySize sorts entries by size descending, keeping the original order
+// for ties so results are the same from run to run
+func sortBySize(entries []*CacheEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+}
+
+// scanPathWithChildren scans a path and its immediate children. Symlinks,
+// whether the root itself or a child, are reported as links and never
+// followed.
+func (s *Scanner) scanPathWithChildren(st *scanState, target Target) (*CacheEntry, error) {
+	path := target.Path
+	info, err := os.Lstat(path)
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +369,9 @@ func (s *Scanner) scanPathWithChildren(path, description string) (*CacheEntry, e
 	entry := &CacheEntry{
 		Name:        filepath.Base(path),
 		Path:        path,
-		Description: description,
+		Description: target.Description,
+		Category:    target.Category,
+		Risk:        target.Risk,
 		LastMod:     info.ModTime(),
 		OldestMod:   info.ModTime(),
 		IsParent:    true,
@@ -116,79 +379,344 @@ func (s *Scanner) scanPathWithChildren(path, description string) (*CacheEntry, e
 		Depth:       0,
 	}
 
-	// Read immediate children
-	dirEntries, err := os.ReadDir(path)
-	if err != nil {
+	if isSymlink(info) {
+		entry.IsParent = false
+		markSymlink(entry)
+		entry.FileCount = 1
+		s.addFile(st, info, entry, entry)
+		return entry, nil
+	}
+	entry.IsDir = info.IsDir()
+
+	if err := s.sizeChildren(st, entry, entry, info); err != nil {
 		// If we can't read children, just scan the whole thing
 		entry.IsParent = false
-		s.scanSize(entry)
+		s.scanSize(st, entry, entry)
 		return entry, nil
 	}
+	if s.Shallow {
+		// The children were only sized to spread the walk over the workers;
+		// their bytes are in entry already
+		entry.Children = nil
+		entry.Listed = false
+		entry.IsParent = false
+	}
+	return entry, nil
+}
 
-	var children []*CacheEntry
-	for _, de := range dirEntries {
-		childPath := filepath.Join(path, de.Name())
-		childInfo, err := os.Stat(childPath)
+// ListTargets returns a placeholder entry for every target that exists,
+// without sizing anything, so there is something to show right away
+func (s *Scanner) ListTargets() []*CacheEntry {
+	var entries []*CacheEntry
+	for _, target := range outermost(s.GetAllowedPaths()) {
+		info, err := os.Lstat(target.Path)
+		if err != nil {
+			continue
+		}
+		entry := &CacheEntry{
+			Name:        filepath.Base(target.Path),
+			Path:        target.Path,
+			Description: target.Description,
+			Category:    target.Category,
+			Risk:        target.Risk,
+			LastMod:     info.ModTime(),
+			OldestMod:   info.ModTime(),
+			IsDir:       info.IsDir(),
+			Pending:     true,
+		}
+		if isSymlink(info) {
+			markSymlink(entry)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ScanChildren lists and sizes the items directly inside entry, one level
+// below it, for drilling into a directory past the levels Scan reports.
+// entry itself is left untouched; the caller attaches the result. Hard links
+// are resolved within entry alone.
+func (s *Scanner) ScanChildren(ctx context.Context, entry *CacheEntry) ([]*CacheEntry, error) {
+	info, err := os.Lstat(entry.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", entry.Path)
+	}
+
+	st := s.newScanState(ctx, 1)
+	parent := &CacheEntry{Path: entry.Path, Depth: entry.Depth, LastMod: info.ModTime(), OldestMod: info.ModTime()}
+	err = s.sizeChildren(st, parent, parent, info)
+	st.progress.close()
+	if err != nil {
+		return nil, err
+	}
+	st.links.resolve()
+	pruneChildren(parent)
+	return parent.Children, ctx.Err()
+}
+
+// sizeChildren reads the items directly inside entry, which lives in target,
+// sizes each in parallel and adds them up into entry
+func (s *Scanner) sizeChildren(st *scanState, entry, target *CacheEntry, info os.FileInfo) error {
+	rootDev, _, _ := fileIdentity(info)
+	dirEntries, err := os.ReadDir(entry.Path)
+	if err != nil {
+		return err
+	}
+
+	// Size every child in parallel, each into its own slot
+	scanned := make([]*CacheEntry, len(dirEntries))
+	var wg sync.WaitGroup
+	for i, de := range dirEntries {
+		if st.ctx.Err() != nil {
+			break
+		}
+		childPath := filepath.Join(entry.Path, de.Name())
+		childInfo, err := os.Lstat(childPath)
 		if err != nil {
 			continue
 		}
+		if s.OneFileSystem && !sameDevice(childInfo, rootDev) {
+			continue // Something is mounted here
+		}
 
 		child := &CacheEntry{
-			Name:     de.Name(),
-			Path:     childPath,
-			LastMod:  childInfo.ModTime(),
+			Name:      de.Name(),
+			Path:      childPath,
+			LastMod:   childInfo.ModTime(),
 			OldestMod: childInfo.ModTime(),
-			Depth:    1,
+			Depth:     entry.Depth + 1,
+			IsDir:     childInfo.IsDir(),
+		}
+		scanned[i] = child
+		if t, ok := st.targets[childPath]; ok {
+			// A target nested inside this one keeps its label and risk
+			child.Description, child.Category, child.Risk = t.Description, t.Category, t.Risk
+		}
+		if isSymlink(childInfo) {
+			markSymlink(child)
+		}
+		if _, ok := st.excludes.match(childPath); ok {
+			// Still sized, so the list can show where the bytes are
+			child.Excluded = true
 		}
 
-		// Calculate size for each child
+		// Calculate size for each child; a symlink counts as the link itself
 		if childInfo.IsDir() {
-			s.scanSize(child)
+			wg.Go(func() { s.scanSize(st, child, target) })
 		} else {
-			child.Size = childInfo.Size()
 			child.FileCount = 1
+			s.addFile(st, childInfo, child, target)
 		}
+	}
+	wg.Wait()
 
-		if child.Size > 0 {
-			children = append(children, child)
-			entry.Size += child.Size
-			entry.FileCount += child.FileCount
-			if child.LastMod.After(entry.LastMod) {
-				entry.LastMod = child.LastMod
-			}
+	// Empty children stay until pruneChildren: hard links resolved after the
+	// walk may still add bytes to them
+	for _, child := range scanned {
+		if child == nil {
+			continue
+		}
+		entry.Children = append(entry.Children, child)
+		if child.Excluded {
+			entry.ExcludedSize += child.Size
+			continue
+		}
+		entry.ExcludedSize += child.ExcludedSize
+		entry.Size += child.Size
+		entry.AllocSize += child.AllocSize
+		entry.FileCount += child.FileCount
+		entry.Ages.Merge(&child.Ages)
+		if child.LastMod.After(entry.LastMod) {
+			entry.LastMod = child.LastMod
 		}
 	}
+	entry.Listed = true
+	return nil
+}
 
-	// Sort children by size
-	sort.Slice(children, func(i, j int) bool {
-		return children[i].Size > children[j].Size
-	})
+// addFile adds a file's size to entry. Files with several hard links are
+// set aside and charged once the whole scan is done (see linkTracker);
+// excluded entries are never charged with anyone else's bytes, so they skip
+// that.
+func (s *Scanner) addFile(st *scanState, info os.FileInfo, entry, target *CacheEntry) {
+	if id, nlink, ok := fileIdentity(info); ok && nlink > 1 && !entry.Excluded {
+		l := cachedLink{
+			Dev: id.dev, Ino: id.ino, Nlink: nlink,
+			Size: info.Size(), Alloc: allocatedSize(info), ModTime: info.ModTime().UnixNano(),
+		}
+		if st.links.record(l, entry, target) {
+			st.progress.addFile(info.Size())
+		}
+		return
+	}
+	entry.Size += info.Size()
+	entry.AllocSize += allocatedSize(info)
+	entry.Ages.Add(info.ModTime(), st.now, info.Size())
+	st.progress.addFile(info.Size())
+	if !entry.Excluded {
+		st.largest.offer(entry.Path, newCachedFile(info), target)
+	}
+}
 
-	entry.Children = children
+// scanSize recursively calculates size of a directory inside target. It holds
+// one worker slot for the duration of the walk and stops early if the scan is
+// cancelled. It does not follow symlinks. Excluded paths inside the walk
+// count toward ExcludedSize instead of Size.
+func (s *Scanner) scanSize(st *scanState, entry, target *CacheEntry) {
+	select {
+	case st.sem <- struct{}{}:
+	case <-st.ctx.Done():
+		return
+	}
+	defer func() { <-st.sem }()
 
-	// If no children or only one, don't show as expandable
-	if len(children) <= 1 {
-		entry.IsParent = false
+	info, err := os.Lstat(entry.Path)
+	if err != nil || st.ctx.Err() != nil {
+		return
+	}
+	if !info.IsDir() {
+		s.addFile(st, info, entry, target)
+		entry.FileCount++
+		if info.ModTime().After(entry.LastMod) {
+			entry.LastMod = info.ModTime()
+		}
+		if info.ModTime().Before(entry.OldestMod) {
+			entry.OldestMod = info.ModTime()
+		}
+		return
 	}
+	rootDev, _, _ := fileIdentity(info)
+	s.sizeDir(st, entry, target, entry.Path, info, rootDev)
+}
 
-	return entry, nil
+// sizeDir adds the directory at path, and everything below it, to entry. A
+// directory that hasn't changed since the last scan is taken from the scan
+// cache instead of being read. Excluded entries count everything, so they
+// bypass the cache.
+func (s *Scanner) sizeDir(st *scanState, entry, target *CacheEntry, path string, info os.FileInfo, rootDev fileID) {
+	if st.ctx.Err() != nil {
+		return
+	}
+	id, _, ok := fileIdentity(info)
+	useCache := ok && st.cache != nil && !entry.Excluded
+
+	var rec *dirRecord
+	if useCache {
+		rec = st.cache.lookup(path, id, info.ModTime())
+	}
+	if rec == nil {
+		var err error
+		if rec, err = s.readDir(st, path, id, info, entry.Excluded); err != nil {
+			return
+		}
+		if useCache {
+			st.cache.store(path, rec)
+		}
+	}
+	s.addRecord(st, rec, path, entry, target)
+
+	for _, name := range rec.Subdirs {
+		sub := filepath.Join(path, name)
+		subInfo, err := os.Lstat(sub)
+		if err != nil || !subInfo.IsDir() {
+			continue
+		}
+		if s.OneFileSystem && !sameDevice(subInfo, rootDev) {
+			continue // Something is mounted here
+		}
+		if !entry.Excluded {
+			if _, ok := st.excludes.match(sub); ok {
+				s.sizeExcluded(st, entry, sub, rootDev)
+				continue
+			}
+		}
+		s.sizeDir(st, entry, target, sub, subInfo, rootDev)
+	}
 }
 
-// scanSize recursively calculates size of a directory
-func (s *Scanner) scanSize(entry *CacheEntry) {
-	filepath.Walk(entry.Path, func(p string, info os.FileInfo, err error) error {
+// readDir builds the record of the directory at path from disk. With all,
+// exclude rules are ignored and hard links counted like any other file, as
+// for an excluded entry.
+func (s *Scanner) readDir(st *scanState, path string, id fileID, info os.FileInfo, all bool) (*dirRecord, error) {
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := &dirRecord{Dev: id.dev, Ino: id.ino, ModTime: info.ModTime().UnixNano()}
+	for _, de := range dirEntries {
+		if err := st.ctx.Err(); err != nil {
+			return nil, err // Don't cache a half-read directory
+		}
+		if de.IsDir() {
+			rec.Subdirs = append(rec.Subdirs, de.Name())
+			continue
+		}
+		p := filepath.Join(path, de.Name())
+		fileInfo, err := os.Lstat(p)
+		if err != nil {
+			continue
+		}
+		if !all {
+			if _, ok := st.excludes.match(p); ok {
+				rec.Excluded += fileInfo.Size()
+				continue
+			}
+		}
+		rec.addFile(fileInfo, !all)
+	}
+	rec.trimLarge()
+	return rec, nil
+}
+
+// addRecord adds the own files of the directory at path to entry
+func (s *Scanner) addRecord(st *scanState, rec *dirRecord, path string, entry, target *CacheEntry) {
+	entry.Size += rec.Size
+	entry.AllocSize += rec.Alloc
+	entry.FileCount += rec.Files
+	entry.ExcludedSize += rec.Excluded
+	for hour, files := range rec.Hours {
+		entry.Ages.addFiles(time.Unix(hour*3600, 0), st.now, files)
+	}
+	if rec.Files > 0 {
+		if newest := time.Unix(0, rec.Newest); newest.After(entry.LastMod) {
+			entry.LastMod = newest
+		}
+		if oldest := time.Unix(0, rec.Oldest); oldest.Before(entry.OldestMod) {
+			entry.OldestMod = oldest
+		}
+	}
+	st.progress.addFiles(rec.Files-len(rec.Links), rec.Size+rec.Excluded)
+	if !entry.Excluded {
+		for _, f := range rec.Large {
+			st.largest.offer(filepath.Join(path, f.Name), f, target)
+		}
+	}
+	for _, l := range rec.Links {
+		if st.links.record(l, entry, target) {
+			st.progress.addFile(l.Size)
+		}
+	}
+}
+
+// sizeExcluded adds everything below an excluded directory to entry's
+// ExcludedSize
+func (s *Scanner) sizeExcluded(st *scanState, entry *CacheEntry, path string, rootDev fileID) {
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if ctxErr := st.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			return nil
 		}
+		if s.OneFileSystem && info.IsDir() && !sameDevice(info, rootDev) {
+			return filepath.SkipDir
+		}
 		if !info.IsDir() {
-			entry.Size += info.Size()
-			entry.FileCount++
-			if info.ModTime().After(entry.LastMod) {
-				entry.LastMod = info.ModTime()
-			}
-			if info.ModTime().Before(entry.OldestMod) {
-				entry.OldestMod = info.ModTime()
-			}
+			entry.ExcludedSize += info.Size()
+			st.progress.addFile(info.Size())
 		}
 		return nil
 	})
@@ -217,7 +745,7 @@ func FormatSize(bytes int64) string {
 // ShortenPath shortens a path for display
 func ShortenPath(path string) string {
 	home, _ := os.UserHomeDir()
-	if home != "" {
+	if home != "" && Within(path, home) {
 		return "~" + path[len(home):]
 	}
 	return path
@@ -0,0 +1,169 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// buildTree fills dir with nested directories of files of varied sizes,
+// including some of equal size to exercise the tie order
+func buildTree(t *testing.T, dir string) {
+	t.Helper()
+	for i := range 6 {
+		sub := filepath.Join(dir, fmt.Sprintf("pkg%d", i))
+		for j := range 5 {
+			writeFile(t, filepath.Join(sub, fmt.Sprintf("f%d", j)), 1000*(i%3+1)+j)
+			writeFile(t, filepath.Join(sub, "deep", fmt.Sprintf("d%d", j), "blob"), 4096*(j+1))
+		}
+	}
+	writeFile(t, filepath.Join(dir, "loose"), 12345)
+}
+
+// sameEntries fails unless got and want list the same entries, in the same
+// order, with the same totals, all the way down
+func sameEntries(t *testing.T, got, want []*CacheEntry) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Path != w.Path || g.Size != w.Size || g.AllocSize != w.AllocSize ||
+			g.SharedSize != w.SharedSize || g.FileCount != w.FileCount || g.Ages != w.Ages {
+			t.Fatalf("entry %d = %s %d/%d/%d files %d, want %s %d/%d/%d files %d", i,
+				g.Path, g.Size, g.AllocSize, g.SharedSize, g.FileCount,
+				w.Path, w.Size, w.AllocSize, w.SharedSize, w.FileCount)
+		}
+		sameEntries(t, g.Children, w.Children)
+	}
+}
+
+func TestParallelScanMatchesSerial(t *testing.T) {
+	f := newFixture(t)
+	buildTree(t, f.root)
+	buildTree(t, filepath.Join(f.home, ".gradle", "caches"))
+
+	scan := func(workers int) *ScanResult {
+		s := f.scanner()
+		s.Workers = workers
+		result, err := s.Scan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	serial := scan(1)
+	if len(serial.Entries) == 0 {
+		t.Fatal("serial scan found nothing")
+	}
+	for range 5 {
+		parallel := scan(8)
+		sameEntries(t, parallel.Entries, serial.Entries)
+		if parallel.TotalSize != serial.TotalSize || parallel.TotalAllocSize != serial.TotalAllocSize {
+			t.Errorf("totals = %d/%d, want %d/%d", parallel.TotalSize, parallel.TotalAllocSize,
+				serial.TotalSize, serial.TotalAllocSize)
+		}
+	}
+}
+
+func TestNestedTargetsCountOnce(t *testing.T) {
+	f := newFixture(t)
+	writeFile(t, filepath.Join(f.root, "pkg", "a"), 1000)
+	writeFile(t, filepath.Join(f.home, ".cache", "other", "b"), 500)
+	s := f.scanner()
+	s.Profile = ""
+	cache := filepath.Join(f.home, ".cache")
+	s.Config = &Config{Targets: []TargetConfig{{Path: cache, Description: "All Caches"}}}
+
+	result, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Path != cache {
+		t.Fatalf("entries = %v, want just %s", result.Entries, cache)
+	}
+	if result.TotalSize != 1500 {
+		t.Errorf("total = %d, want 1500 with the yarn cache counted once", result.TotalSize)
+	}
+	yarn := childNamed(t, result.Entries[0], "yarn")
+	if yarn.Description != "Yarn Cache" || yarn.Risk == "" {
+		t.Errorf("yarn child = %q, risk %q; want the target's label and risk", yarn.Description, yarn.Risk)
+	}
+
+	// Without the outer target, the inner one stands on its own
+	s.Profile = ProfileDeveloper
+	if e := scanEntry(t, s, f.root); e.Size != 1000 {
+		t.Errorf("yarn size = %d, want 1000", e.Size)
+	}
+}
+
+func TestShallowScanMatchesFullScan(t *testing.T) {
+	f := newFixture(t)
+	buildTree(t, f.root)
+	link(t, filepath.Join(f.root, "loose"), filepath.Join(f.root, "pkg0", "loose-link"))
+
+	full := scanEntry(t, f.scanner(), f.root)
+	s := f.scanner()
+	s.Shallow = true
+	shallow := scanEntry(t, s, f.root)
+
+	if shallow.Size != full.Size || shallow.AllocSize != full.AllocSize ||
+		shallow.FileCount != full.FileCount || shallow.SharedSize != full.SharedSize {
+		t.Errorf("shallow = %d/%d in %d files, %d shared; want %d/%d in %d, %d shared",
+			shallow.Size, shallow.AllocSize, shallow.FileCount, shallow.SharedSize,
+			full.Size, full.AllocSize, full.FileCount, full.SharedSize)
+	}
+	if len(shallow.Children) != 0 || shallow.Listed {
+		t.Errorf("shallow scan listed %d children", len(shallow.Children))
+	}
+	if len(full.Children) == 0 {
+		t.Error("full scan listed no children")
+	}
+}
+
+func TestScanReportsProgress(t *testing.T) {
+	f := newFixture(t)
+	buildTree(t, f.root)
+	buildTree(t, filepath.Join(f.home, ".gradle", "caches"))
+
+	var reports []Progress
+	s := f.scanner()
+	s.OnProgress = func(p Progress) { reports = append(reports, p) }
+	result, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+
+	var prev Progress
+	var doneSize int64
+	for i, p := range reports {
+		if p.FilesSeen < prev.FilesSeen || p.BytesCounted < prev.BytesCounted || p.TargetsDone < prev.TargetsDone {
+			t.Fatalf("report %d = %+v went back from %+v", i, p, prev)
+		}
+		if p.Done != nil {
+			doneSize += p.Done.Size
+			if p.Done.Children != nil {
+				t.Errorf("finished %s reported with its children", p.Done.Path)
+			}
+		}
+		prev = p
+	}
+
+	var files int
+	for _, e := range result.Entries {
+		files += e.FileCount
+	}
+	last := reports[len(reports)-1]
+	if last.TargetsDone != last.TargetsTotal || last.TargetsTotal == 0 {
+		t.Errorf("last report has %d of %d targets done", last.TargetsDone, last.TargetsTotal)
+	}
+	if last.FilesSeen != int64(files) || last.BytesCounted != result.TotalSize || doneSize != result.TotalSize {
+		t.Errorf("last report has %d files and %d bytes, finished targets %d bytes; want %d files and %d bytes",
+			last.FilesSeen, last.BytesCounted, doneSize, files, result.TotalSize)
+	}
+}
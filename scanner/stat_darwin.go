@@ -0,0 +1,17 @@
+package scanner
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns when a file was last read, falling back to its
+// modification time
+func accessTime(info os.FileInfo) time.Time {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(st.Atimespec.Unix())
+}
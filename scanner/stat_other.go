@@ -0,0 +1,24 @@
+//go:build !darwin && !linux
+
+package scanner
+
+import (
+	"os"
+	"time"
+)
+
+// allocatedSize falls back to the apparent size where block counts aren't
+// available
+func allocatedSize(info os.FileInfo) int64 {
+	return info.Size()
+}
+
+// fileIdentity is unavailable here, so hard links are counted per path
+func fileIdentity(info os.FileInfo) (id fileID, nlink uint64, ok bool) {
+	return fileID{}, 0, false
+}
+
+// accessTime falls back to the modification time
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
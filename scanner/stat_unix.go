@@ -0,0 +1,29 @@
+//go:build darwin || linux
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocatedSize returns the bytes a file occupies on disk, from the block
+// count in its stat result. Sparse files come out smaller than their
+// apparent size; small files come out rounded up to whole blocks.
+func allocatedSize(info os.FileInfo) int64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	// st_blocks is always in 512-byte units, regardless of st_blksize
+	return int64(st.Blocks) * 512
+}
+
+// fileIdentity returns the device/inode pair and link count of a file
+func fileIdentity(info os.FileInfo) (id fileID, nlink uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, uint64(st.Nlink), true
+}
@@ -0,0 +1,44 @@
+//go:build darwin || linux
+
+package scanner
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// blocks returns the bytes the file at path occupies on disk, straight from
+// stat
+func blocks(t *testing.T, path string) int64 {
+	t.Helper()
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		t.Fatal(err)
+	}
+	return int64(st.Blocks) * 512
+}
+
+func TestScanCountsAllocatedSize(t *testing.T) {
+	f := newFixture(t)
+	sparse := filepath.Join(f.root, "sparse", "disk.img")
+	sparseFile(t, sparse, 64<<20)
+	tiny := filepath.Join(f.root, "tiny", "one")
+	writeFile(t, tiny, 1)
+	if blocks(t, sparse) >= 64<<20 {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+
+	entry := scanEntry(t, f.scanner(), f.root)
+	if s := childNamed(t, entry, "sparse"); s.Size != 64<<20 || s.AllocSize != blocks(t, sparse) {
+		t.Errorf("sparse file = %d bytes, %d on disk; want %d, %d", s.Size, s.AllocSize, 64<<20, blocks(t, sparse))
+	}
+	// A small file takes a whole block, unless the filesystem stores it
+	// inline
+	if s := childNamed(t, entry, "tiny"); s.Size != 1 || s.AllocSize != blocks(t, tiny) {
+		t.Errorf("tiny file = %d bytes, %d on disk; want 1, %d", s.Size, s.AllocSize, blocks(t, tiny))
+	}
+	if entry.AllocSize != blocks(t, sparse)+blocks(t, tiny) || entry.AllocSize >= entry.Size {
+		t.Errorf("target = %d bytes, %d on disk; want the files' blocks", entry.Size, entry.AllocSize)
+	}
+}
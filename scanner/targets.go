@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Built-in target categories. Config files may use any other name too.
+const (
+	CategorySystem    = "System"
+	CategoryLogs      = "Logs"
+	CategoryDeveloper = "Developer"
+	CategoryBrowser   = "Browser"
+	CategoryTrash     = "Trash"
+)
+
+// Target is an allowlisted path the scanner may size and clean
+type Target struct {
+	Path        string
+	Description string
+	Category    string
+	Risk        string // What cleaning it costs you, shown before deleting
+}
+
+// GetAllowedPaths returns the list of allowed paths to scan: the built-in
+// catalog for this platform (see platformTargets) merged with s.Config, and
+// narrowed down to s.Profile
+func (s *Scanner) GetAllowedPaths() []Target {
+	targets := append(s.platformTargets(), s.commonTargets()...)
+	if s.Config != nil {
+		targets = s.Config.merge(targets)
+	}
+	if p, err := s.FindProfile(s.Profile); err == nil {
+		targets = p.filter(targets)
+	}
+	return targets
+}
+
+// commonTargets are tool caches that live in the same place everywhere
+func (s *Scanner) commonTargets() []Target {
+	return []Target{
+		{filepath.Join(s.HomeDir, ".npm", "_cacache"), "npm Cache", CategoryDeveloper, "Packages are re-downloaded on the next install"},
+		{filepath.Join(s.HomeDir, ".gradle", "caches"), "Gradle Cache", CategoryDeveloper, "Dependencies are re-downloaded and builds are slower once"},
+		{filepath.Join(s.HomeDir, ".cargo", "registry"), "Cargo Registry", CategoryDeveloper, "Crates are re-downloaded on the next build"},
+	}
+}
+
+// xdgDir returns the XDG base directory named by env, or fallback (relative
+// to the home directory) when it is unset or not absolute, as the spec asks
+func (s *Scanner) xdgDir(env string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(append([]string{s.HomeDir}, fallback...)...)
+}
@@ -0,0 +1,18 @@
+package scanner
+
+import "path/filepath"
+
+// platformTargets returns the macOS cache and log locations
+func (s *Scanner) platformTargets() []Target {
+	return []Target{
+		{filepath.Join(s.HomeDir, "Library", "Caches"), "System & App Caches", CategorySystem, "Apps rebuild their caches; expect slower first launches"},
+		{filepath.Join(s.HomeDir, "Library", "Logs"), "Log Files", CategoryLogs, "Old crash and diagnostic logs are gone"},
+		{filepath.Join(s.HomeDir, "Library", "Developer", "Xcode", "DerivedData"), "Xcode Build Data", CategoryDeveloper, "Next Xcode builds and indexing start from scratch"},
+		{filepath.Join(s.HomeDir, "Library", "Developer", "Xcode", "Archives"), "Xcode Archives", CategoryDeveloper, "Archived builds and their dSYMs can't be re-uploaded or symbolicated"},
+		{filepath.Join(s.HomeDir, ".cache", "yarn"), "Yarn Cache", CategoryDeveloper, "Packages are re-downloaded on the next install"},
+		{filepath.Join(s.HomeDir, "Library", "Caches", "pip"), "Python pip Cache", CategoryDeveloper, "Wheels are re-downloaded or rebuilt on the next install"},
+		{filepath.Join(s.HomeDir, "Library", "Caches", "Homebrew"), "Homebrew Cache", CategoryDeveloper, "Bottles are re-downloaded on the next install or upgrade"},
+		{filepath.Join(s.HomeDir, "Library", "Caches", "Google", "Chrome"), "Chrome Cache", CategoryBrowser, "Pages load slower until the cache refills"},
+		{filepath.Join(s.HomeDir, "Library", "Caches", "com.apple.Safari"), "Safari Cache", CategoryBrowser, "Pages load slower until the cache refills"},
+	}
+}
@@ -0,0 +1,23 @@
+package scanner
+
+import "path/filepath"
+
+// platformTargets returns the Linux cache locations, following the XDG base
+// directory spec
+func (s *Scanner) platformTargets() []Target {
+	cache := s.xdgDir("XDG_CACHE_HOME", ".cache")
+	data := s.xdgDir("XDG_DATA_HOME", ".local", "share")
+
+	return []Target{
+		{cache, "User Caches (XDG)", CategorySystem, "Apps rebuild their caches; expect slower first launches"},
+		{filepath.Join(cache, "go-build"), "Go Build Cache", CategoryDeveloper, "Next Go builds and tests recompile from scratch"},
+		{filepath.Join(cache, "pip"), "Python pip Cache", CategoryDeveloper, "Wheels are re-downloaded or rebuilt on the next install"},
+		{filepath.Join(cache, "yarn"), "Yarn Cache", CategoryDeveloper, "Packages are re-downloaded on the next install"},
+		{filepath.Join(cache, "JetBrains"), "JetBrains IDE Caches", CategoryDeveloper, "IDEs re-index open projects on next start"},
+		{filepath.Join(cache, "thumbnails"), "Thumbnails", CategorySystem, "File managers regenerate previews as you browse"},
+		{filepath.Join(cache, "mozilla"), "Firefox Cache", CategoryBrowser, "Pages load slower until the cache refills"},
+		{filepath.Join(cache, "google-chrome"), "Chrome Cache", CategoryBrowser, "Pages load slower until the cache refills"},
+		{filepath.Join(cache, "chromium"), "Chromium Cache", CategoryBrowser, "Pages load slower until the cache refills"},
+		{filepath.Join(data, "Trash"), "Trash", CategoryTrash, "Trashed files can no longer be restored"},
+	}
+}
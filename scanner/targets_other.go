@@ -0,0 +1,9 @@
+//go:build !darwin && !linux
+
+package scanner
+
+// platformTargets has no platform-specific locations here; only the common
+// tool caches are scanned
+func (s *Scanner) platformTargets() []Target {
+	return nil
+}
@@ -0,0 +1,387 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// WatchInterval is how long Watch collects filesystem events before
+// reporting them, so a busy build produces one update per interval rather
+// than one per file
+const WatchInterval = time.Second
+
+// ErrWatchUnsupported is returned by Watch where the platform has no
+// filesystem notifications it can use
+var ErrWatchUnsupported = errors.New("watching for changes isn't supported on this platform")
+
+// Change is how one watched directory changed since the last report. The
+// deltas apply to the directory and to every entry containing it.
+type Change struct {
+	Dir          string
+	Size         int64
+	AllocSize    int64
+	FileCount    int
+	ExcludedSize int64
+	Ages         AgeHistogram
+	LastMod      time.Time // Newest modification among added files, zero if none
+
+	// Current state of the changed items directly in Dir, and the names of
+	// the ones that are gone, for updating a listing of Dir
+	Updated []*CacheEntry
+	Removed []string
+}
+
+// Affects reports whether e is Dir or contains it
+func (c *Change) Affects(e *CacheEntry) bool {
+	return Within(c.Dir, e.Path)
+}
+
+// Apply adds the change's deltas to e, which it affects
+func (c *Change) Apply(e *CacheEntry) {
+	e.Size = max(e.Size+c.Size, 0)
+	e.AllocSize = max(e.AllocSize+c.AllocSize, 0)
+	e.FileCount = max(e.FileCount+c.FileCount, 0)
+	e.ExcludedSize = max(e.ExcludedSize+c.ExcludedSize, 0)
+	e.Ages.Merge(&c.Ages)
+	if c.LastMod.After(e.LastMod) {
+		e.LastMod = c.LastMod
+	}
+}
+
+func (c *Change) empty() bool {
+	return c.Size == 0 && c.AllocSize == 0 && c.FileCount == 0 && c.ExcludedSize == 0 &&
+		len(c.Updated) == 0 && len(c.Removed) == 0
+}
+
+// add adds sign times o's deltas to c
+func (c *Change) add(o *Change, sign int) {
+	c.Size += int64(sign) * o.Size
+	c.AllocSize += int64(sign) * o.AllocSize
+	c.FileCount += sign * o.FileCount
+	c.ExcludedSize += int64(sign) * o.ExcludedSize
+	for i := range c.Ages {
+		c.Ages[i].Files += sign * o.Ages[i].Files
+		c.Ages[i].Size += int64(sign) * o.Ages[i].Size
+	}
+	if sign > 0 && o.LastMod.After(c.LastMod) {
+		c.LastMod = o.LastMod
+	}
+}
+
+// addRecord adds sign times a directory's own files to c. Files with other
+// hard links only count toward FileCount; where their bytes belong is left
+// to the next scan.
+func (c *Change) addRecord(r *dirRecord, now time.Time, sign int) {
+	var d Change
+	d.Size, d.AllocSize, d.FileCount, d.ExcludedSize = r.Size, r.Alloc, r.Files, r.Excluded
+	for hour, files := range r.Hours {
+		d.Ages.addFiles(time.Unix(hour*3600, 0), now, files)
+	}
+	if r.Files > 0 {
+		d.LastMod = time.Unix(0, r.Newest)
+	}
+	c.add(&d, sign)
+}
+
+// notifier is a platform's source of directory change events
+type notifier interface {
+	add(dir string) error // Watch dir itself, not its subdirectories
+	remove(dir string)
+	run(ctx context.Context, events chan<- notifyEvent) // Until ctx is done or close
+	close() error
+}
+
+// notifyEvent reports that the item name in dir changed. An empty name is
+// dir itself; overflow means events were lost.
+type notifyEvent struct {
+	dir      string
+	name     string
+	overflow bool
+}
+
+// watcher keeps a record of every watched directory, to turn events into
+// size changes without walking anything but the changed directories
+type watcher struct {
+	s       *Scanner
+	st      *scanState
+	n       notifier
+	roots   map[string]fileID // Device of each root, for OneFileSystem
+	records map[string]*dirRecord
+}
+
+// Watch follows changes below roots until ctx is done, calling onChange at
+// most once per WatchInterval with what changed. Directories are read from
+// s.Cache where it's still valid. Only Linux (inotify) is supported.
+func (s *Scanner) Watch(ctx context.Context, roots []string, onChange func([]Change)) error {
+	n, err := newNotifier()
+	if err != nil {
+		return err
+	}
+	return s.watch(ctx, n, WatchInterval, roots, onChange)
+}
+
+// watch is Watch with events from n, reported every interval
+func (s *Scanner) watch(ctx context.Context, n notifier, interval time.Duration, roots []string, onChange func([]Change)) error {
+	defer n.close()
+
+	st := s.newScanState(ctx, len(roots))
+	defer st.progress.close()
+	w := &watcher{
+		s:       s,
+		st:      st,
+		n:       n,
+		roots:   make(map[string]fileID),
+		records: make(map[string]*dirRecord),
+	}
+
+	events := make(chan notifyEvent, 256)
+	go n.run(ctx, events)
+
+	for _, root := range roots {
+		info, err := os.Lstat(root)
+		if err != nil || !info.IsDir() {
+			continue // Missing, or a symlink that is never followed
+		}
+		w.roots[root], _, _ = fileIdentity(info)
+		if _, err := w.track(root, info); err != nil {
+			return err
+		}
+	}
+
+	pending := make(map[string]map[string]bool)
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-events:
+			if ev.overflow {
+				// Events were dropped: look at every directory again
+				for dir := range w.records {
+					pending[dir] = nil
+				}
+			} else {
+				if pending[ev.dir] == nil {
+					pending[ev.dir] = make(map[string]bool)
+				}
+				pending[ev.dir][ev.name] = true
+			}
+			if flush == nil {
+				flush = time.After(interval)
+			}
+		case <-flush:
+			flush = nil
+			changes, err := w.flush(pending)
+			pending = make(map[string]map[string]bool)
+			if len(changes) > 0 {
+				onChange(changes)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// rootDev returns the device of the root containing dir
+func (w *watcher) rootDev(dir string) fileID {
+	var best string
+	for root := range w.roots {
+		if Within(dir, root) && len(root) > len(best) {
+			best = root
+		}
+	}
+	return w.roots[best]
+}
+
+// track starts watching dir and everything below it, and returns their
+// totals
+func (w *watcher) track(dir string, info os.FileInfo) (Change, error) {
+	total := Change{Dir: dir}
+	id, _, _ := fileIdentity(info)
+	var rec *dirRecord
+	if w.st.cache != nil {
+		rec = w.st.cache.lookup(dir, id, info.ModTime())
+	}
+	if rec == nil {
+		var err error
+		if rec, err = w.s.readDir(w.st, dir, id, info, false); err != nil {
+			return total, nil // Unreadable directories are skipped, as in Scan
+		}
+	}
+	if err := w.n.add(dir); err != nil {
+		return total, err
+	}
+	w.records[dir] = rec
+	total.addRecord(rec, w.st.now, 1)
+
+	for _, name := range rec.Subdirs {
+		subInfo, ok := w.eligible(filepath.Join(dir, name))
+		if !ok {
+			continue
+		}
+		t, err := w.track(filepath.Join(dir, name), subInfo)
+		total.add(&t, 1)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// eligible reports whether the subdirectory at path is sized like Scan
+// would: a real directory, not excluded, and on the root's device if
+// OneFileSystem is set
+func (w *watcher) eligible(path string) (os.FileInfo, bool) {
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return nil, false
+	}
+	if w.s.OneFileSystem && !sameDevice(info, w.rootDev(path)) {
+		return nil, false
+	}
+	_, excluded := w.st.excludes.match(path)
+	return info, !excluded
+}
+
+// untrack stops watching dir and everything below it, and returns their
+// totals
+func (w *watcher) untrack(dir string) Change {
+	total := Change{Dir: dir}
+	for path, rec := range w.records {
+		if Within(path, dir) {
+			total.addRecord(rec, w.st.now, 1)
+			w.n.remove(path)
+			delete(w.records, path)
+		}
+	}
+	return total
+}
+
+// flush turns the pending events, by directory and item name, into changes.
+// Parents go first, so a directory that appeared or vanished along with its
+// parent's listing is handled there.
+func (w *watcher) flush(pending map[string]map[string]bool) ([]Change, error) {
+	w.st.now = time.Now()
+	dirs := make([]string, 0, len(pending))
+	for dir := range pending {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+
+	var changes []Change
+	for _, dir := range dirs {
+		old := w.records[dir]
+		if old == nil {
+			continue
+		}
+		c := Change{Dir: dir}
+		info, err := os.Lstat(dir)
+		if err != nil || !info.IsDir() {
+			if _, ok := w.roots[dir]; ok {
+				gone := w.untrack(dir)
+				c.add(&gone, -1)
+				changes = append(changes, c)
+			}
+			continue
+		}
+		id, _, _ := fileIdentity(info)
+		rec, err := w.s.readDir(w.st, dir, id, info, false)
+		if err != nil {
+			continue
+		}
+		c.addRecord(rec, w.st.now, 1)
+		c.addRecord(old, w.st.now, -1)
+		w.records[dir] = rec
+
+		added := make(map[string]*Change)
+		var trackErr error
+		for _, name := range rec.Subdirs {
+			if slices.Contains(old.Subdirs, name) {
+				continue
+			}
+			sub := filepath.Join(dir, name)
+			subInfo, ok := w.eligible(sub)
+			if !ok {
+				continue
+			}
+			t, err := w.track(sub, subInfo)
+			c.add(&t, 1)
+			added[name] = &t
+			if err != nil {
+				trackErr = err
+			}
+		}
+		for _, name := range old.Subdirs {
+			if !slices.Contains(rec.Subdirs, name) {
+				gone := w.untrack(filepath.Join(dir, name))
+				c.add(&gone, -1)
+			}
+		}
+
+		names := make([]string, 0, len(pending[dir]))
+		for name := range pending[dir] {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			e, exists := w.entryFor(filepath.Join(dir, name), added[name])
+			switch {
+			case !exists:
+				c.Removed = append(c.Removed, name)
+			case e != nil:
+				c.Updated = append(c.Updated, e)
+			}
+		}
+
+		if !c.empty() {
+			changes = append(changes, c)
+		}
+		if trackErr != nil {
+			return changes, trackErr
+		}
+	}
+	return changes, nil
+}
+
+// entryFor describes the item at path for a listing of its directory. A
+// directory only has totals if it's new, given as total; for others e is
+// nil. exists is false if path is gone.
+func (w *watcher) entryFor(path string, total *Change) (e *CacheEntry, exists bool) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, false
+	}
+	e = &CacheEntry{
+		Name:      filepath.Base(path),
+		Path:      path,
+		LastMod:   info.ModTime(),
+		OldestMod: info.ModTime(),
+		IsDir:     info.IsDir(),
+	}
+	if isSymlink(info) {
+		markSymlink(e)
+	}
+	if _, excluded := w.st.excludes.match(path); excluded {
+		e.Excluded = true
+	}
+	switch {
+	case info.IsDir() && total != nil:
+		total.Apply(e)
+	case info.IsDir():
+		return nil, true
+	default:
+		e.Size = info.Size()
+		e.AllocSize = allocatedSize(info)
+		e.FileCount = 1
+		e.Ages.Add(info.ModTime(), w.st.now, info.Size())
+	}
+	return e, true
+}
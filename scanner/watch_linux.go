@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchMask selects the events that can change a directory's size
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF |
+	unix.IN_ONLYDIR | unix.IN_DONT_FOLLOW | unix.IN_EXCL_UNLINK
+
+// inotify watches directories with Linux inotify
+type inotify struct {
+	fd   int
+	file *os.File // Wraps fd so reads don't tie up a thread and close unblocks them
+
+	mu   sync.Mutex
+	wds  map[int]string // Watch descriptor to directory
+	dirs map[string]int
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	return &inotify{
+		fd:   fd,
+		file: os.NewFile(uintptr(fd), "inotify"),
+		wds:  make(map[int]string),
+		dirs: make(map[string]int),
+	}, nil
+}
+
+func (n *inotify) add(dir string) error {
+	wd, err := unix.InotifyAddWatch(n.fd, dir, watchMask)
+	if errors.Is(err, unix.ENOSPC) {
+		return fmt.Errorf("too many directories to watch; raise fs.inotify.max_user_watches (%s)", dir)
+	}
+	if err != nil {
+		// The directory may have vanished already; its parent reports that
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.wds[wd] = dir
+	n.dirs[dir] = wd
+	return nil
+}
+
+func (n *inotify) remove(dir string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	wd, ok := n.dirs[dir]
+	if !ok {
+		return
+	}
+	unix.InotifyRmWatch(n.fd, uint32(wd))
+	delete(n.dirs, dir)
+	delete(n.wds, wd)
+}
+
+func (n *inotify) run(ctx context.Context, events chan<- notifyEvent) {
+	buf := make([]byte, 64*1024)
+	for {
+		k, err := n.file.Read(buf)
+		if err != nil {
+			return // Closed
+		}
+		for off := 0; off+unix.SizeofInotifyEvent <= k; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(raw.Len)]
+			off += unix.SizeofInotifyEvent + int(raw.Len)
+
+			ev, ok := n.event(raw, string(bytes.TrimRight(nameBytes, "\x00")))
+			if !ok {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// event translates a raw inotify event
+func (n *inotify) event(raw *unix.InotifyEvent, name string) (notifyEvent, bool) {
+	if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+		return notifyEvent{overflow: true}, true
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	dir, ok := n.wds[int(raw.Wd)]
+	if !ok {
+		return notifyEvent{}, false
+	}
+	if raw.Mask&unix.IN_IGNORED != 0 {
+		// The kernel dropped the watch, usually because dir is gone
+		delete(n.wds, int(raw.Wd))
+		delete(n.dirs, dir)
+		return notifyEvent{}, false
+	}
+	return notifyEvent{dir: dir, name: name}, true
+}
+
+func (n *inotify) close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchWithInotify(t *testing.T) {
+	f := newFixture(t)
+	sub := filepath.Join(f.root, "sub")
+	writeFile(t, filepath.Join(sub, "a"), 100)
+	n, err := newNotifier()
+	if err != nil {
+		t.Skip(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan []Change, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- f.scanner().watch(ctx, n, 20*time.Millisecond, []string{f.root}, func(c []Change) { changes <- c })
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	// Wait for both directories to be watched
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n.(*inotify).mu.Lock()
+		watched := len(n.(*inotify).dirs)
+		n.(*inotify).mu.Unlock()
+		if watched == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d directories watched, want 2", watched)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Sums up the reports until they add up to want
+	await := func(what string, want int64) {
+		t.Helper()
+		var got int64
+		for got != want {
+			select {
+			case report := <-changes:
+				for _, c := range report {
+					got += c.Size
+				}
+			case err := <-done:
+				t.Fatalf("%s: watch stopped: %v", what, err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: changes add up to %d bytes, want %d", what, got, want)
+			}
+		}
+	}
+
+	writeFile(t, filepath.Join(sub, "b"), 300)
+	await("created", 300)
+	writeFile(t, filepath.Join(sub, "a"), 150)
+	await("modified", 50)
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err)
+	}
+	await("removed", -450)
+}
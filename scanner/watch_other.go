@@ -0,0 +1,7 @@
+//go:build !linux
+
+package scanner
+
+func newNotifier() (notifier, error) {
+	return nil, ErrWatchUnsupported
+}
@@ -0,0 +1,276 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotifier stands in for inotify: tests send the events themselves
+type fakeNotifier struct {
+	limit  int // Watches allowed at once, 0 for no limit
+	events chan notifyEvent
+
+	mu      sync.Mutex
+	watched map[string]bool
+}
+
+func newFakeNotifier(limit int) *fakeNotifier {
+	return &fakeNotifier{limit: limit, events: make(chan notifyEvent, 64), watched: make(map[string]bool)}
+}
+
+func (n *fakeNotifier) add(dir string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.limit > 0 && len(n.watched) >= n.limit {
+		return errors.New("too many directories to watch")
+	}
+	n.watched[dir] = true
+	return nil
+}
+
+func (n *fakeNotifier) remove(dir string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.watched, dir)
+}
+
+func (n *fakeNotifier) run(ctx context.Context, events chan<- notifyEvent) {
+	for {
+		select {
+		case ev := <-n.events:
+			events <- ev
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *fakeNotifier) close() error { return nil }
+
+func (n *fakeNotifier) watching(dir string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.watched[dir]
+}
+
+// watchRun is a watch running in the background, its reports arriving on
+// changes
+type watchRun struct {
+	n       *fakeNotifier
+	changes chan []Change
+	done    chan error
+}
+
+// startWatch watches root with n until the test ends, once the existing
+// directories are all being watched
+func startWatch(t *testing.T, s *Scanner, n *fakeNotifier, interval time.Duration, root string, dirs ...string) *watchRun {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &watchRun{n: n, changes: make(chan []Change, 16), done: make(chan error, 1)}
+	go func() {
+		r.done <- s.watch(ctx, n, interval, []string{root}, func(c []Change) { r.changes <- c })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-r.done
+	})
+	for _, dir := range append([]string{root}, dirs...) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !n.watching(dir) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s isn't watched", dir)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return r
+}
+
+// next returns the next report
+func (r *watchRun) next(t *testing.T) []Change {
+	t.Helper()
+	select {
+	case c := <-r.changes:
+		return c
+	case err := <-r.done:
+		t.Fatalf("watch stopped: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	return nil
+}
+
+// only returns the single change of a report, which must be about dir
+func only(t *testing.T, report []Change, dir string) Change {
+	t.Helper()
+	if len(report) != 1 || report[0].Dir != dir {
+		var dirs []string
+		for _, c := range report {
+			dirs = append(dirs, c.Dir)
+		}
+		t.Fatalf("changes for %q, want %s alone", dirs, dir)
+	}
+	return report[0]
+}
+
+func TestWatchResizesChangedEntries(t *testing.T) {
+	f := newFixture(t)
+	sub := filepath.Join(f.root, "sub")
+	writeFile(t, filepath.Join(sub, "a"), 100)
+	s := f.scanner()
+	entry := scanEntry(t, s, f.root)
+	subEntry := childNamed(t, entry, "sub")
+	r := startWatch(t, s, newFakeNotifier(0), 10*time.Millisecond, f.root, sub)
+
+	// apply updates the scanned entries as the TUI does
+	apply := func(c Change) {
+		for _, e := range []*CacheEntry{entry, subEntry} {
+			if c.Affects(e) {
+				c.Apply(e)
+			}
+		}
+	}
+
+	writeFile(t, filepath.Join(f.root, "new"), 300)
+	r.n.events <- notifyEvent{dir: f.root, name: "new"}
+	c := only(t, r.next(t), f.root)
+	if c.Size != 300 || c.FileCount != 1 || len(c.Updated) != 1 || c.Updated[0].Name != "new" || c.Updated[0].Size != 300 {
+		t.Errorf("created: %+v, want 300 bytes more in new", c)
+	}
+	apply(c)
+
+	writeFile(t, filepath.Join(sub, "a"), 250)
+	r.n.events <- notifyEvent{dir: sub, name: "a"}
+	c = only(t, r.next(t), sub)
+	if c.Size != 150 || c.FileCount != 0 {
+		t.Errorf("modified: %+v, want 150 bytes more in as many files", c)
+	}
+	apply(c)
+
+	if err := os.Remove(filepath.Join(sub, "a")); err != nil {
+		t.Fatal(err)
+	}
+	r.n.events <- notifyEvent{dir: sub, name: "a"}
+	c = only(t, r.next(t), sub)
+	if c.Size != -250 || c.FileCount != -1 || !slices.Equal(c.Removed, []string{"a"}) {
+		t.Errorf("removed: %+v, want a's 250 bytes gone", c)
+	}
+	apply(c)
+
+	// A new directory is read whole and watched from then on
+	dir := filepath.Join(f.root, "dir")
+	writeFile(t, filepath.Join(dir, "deep", "x"), 40)
+	r.n.events <- notifyEvent{dir: f.root, name: "dir"}
+	c = only(t, r.next(t), f.root)
+	if c.Size != 40 || len(c.Updated) != 1 || c.Updated[0].Size != 40 {
+		t.Errorf("new directory: %+v, want its 40 bytes", c)
+	}
+	if !r.n.watching(filepath.Join(dir, "deep")) {
+		t.Error("new directory isn't watched")
+	}
+	apply(c)
+
+	if got, want := entry.Size, scanEntry(t, f.scanner(), f.root).Size; got != want {
+		t.Errorf("watched size = %d, want %d as a new scan finds", got, want)
+	}
+	if subEntry.Size != 0 || subEntry.FileCount != 0 {
+		t.Errorf("sub = %d bytes in %d files, want empty", subEntry.Size, subEntry.FileCount)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	r.n.events <- notifyEvent{dir: f.root, name: "dir"}
+	c = only(t, r.next(t), f.root)
+	if c.Size != -40 || !slices.Equal(c.Removed, []string{"dir"}) {
+		t.Errorf("removed directory: %+v, want its 40 bytes gone", c)
+	}
+	if r.n.watching(filepath.Join(dir, "deep")) {
+		t.Error("removed directory is still watched")
+	}
+}
+
+func TestWatchCollectsEventsPerInterval(t *testing.T) {
+	f := newFixture(t)
+	sub := filepath.Join(f.root, "sub")
+	mkdir(t, sub)
+	r := startWatch(t, f.scanner(), newFakeNotifier(0), 200*time.Millisecond, f.root, sub)
+
+	for i := range 10 {
+		name := string(rune('a' + i))
+		writeFile(t, filepath.Join(f.root, name), 10)
+		writeFile(t, filepath.Join(sub, name), 20)
+		r.n.events <- notifyEvent{dir: f.root, name: name}
+		r.n.events <- notifyEvent{dir: sub, name: name}
+	}
+
+	report := r.next(t)
+	if len(report) != 2 || report[0].Dir != f.root || report[1].Dir != sub {
+		t.Fatalf("report = %+v, want one change for the root, then one for sub", report)
+	}
+	if report[0].Size != 100 || len(report[0].Updated) != 10 || report[1].Size != 200 || len(report[1].Updated) != 10 {
+		t.Errorf("report = %+v, want all 10 files of each directory at once", report)
+	}
+	select {
+	case more := <-r.changes:
+		t.Errorf("second report %+v, want the events in one", more)
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestWatchRereadsEverythingAfterOverflow(t *testing.T) {
+	f := newFixture(t)
+	sub := filepath.Join(f.root, "sub")
+	mkdir(t, sub)
+	r := startWatch(t, f.scanner(), newFakeNotifier(0), 10*time.Millisecond, f.root, sub)
+
+	// Changes whose events were lost
+	writeFile(t, filepath.Join(f.root, "a"), 10)
+	writeFile(t, filepath.Join(sub, "b"), 20)
+	r.n.events <- notifyEvent{overflow: true}
+
+	report := r.next(t)
+	var total int64
+	for _, c := range report {
+		total += c.Size
+	}
+	if len(report) != 2 || total != 30 {
+		t.Errorf("report = %+v, want both directories read again", report)
+	}
+}
+
+func TestWatchStopsPastWatchLimit(t *testing.T) {
+	f := newFixture(t)
+	for _, name := range []string{"a", "b", "c"} {
+		mkdir(t, filepath.Join(f.root, name))
+	}
+	err := f.scanner().watch(context.Background(), newFakeNotifier(3), time.Millisecond, []string{f.root}, func([]Change) {})
+	if err == nil || !strings.Contains(err.Error(), "too many directories") {
+		t.Errorf("err = %v, want the watch limit reported", err)
+	}
+
+	// A directory appearing later can run past the limit too
+	if err := os.RemoveAll(filepath.Join(f.root, "c")); err != nil {
+		t.Fatal(err)
+	}
+	n := newFakeNotifier(3)
+	r := startWatch(t, f.scanner(), n, 10*time.Millisecond, f.root)
+	mkdir(t, filepath.Join(f.root, "d"))
+	n.events <- notifyEvent{dir: f.root, name: "d"}
+	select {
+	case err := <-r.done:
+		r.done <- err // For the cleanup
+		if err == nil || !strings.Contains(err.Error(), "too many directories") {
+			t.Errorf("err = %v, want the watch limit reported", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch kept going past the limit")
+	}
+}
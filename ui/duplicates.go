@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+type duplicatesMsg struct {
+	groups []scanner.DuplicateGroup
+	err    error
+}
+
+// findDuplicates looks for duplicate files across the targets in the
+// background
+func (m *Model) findDuplicates() tea.Cmd {
+	m.findingDupes = true
+	opts := m.opts
+	return func() tea.Msg {
+		s, err := opts.newScanner()
+		if err != nil {
+			return duplicatesMsg{err: err}
+		}
+		groups, err := s.FindDuplicates(context.Background())
+		return duplicatesMsg{groups: groups, err: err}
+	}
+}
+
+// attachDuplicates lists the groups found, each as an entry whose children
+// are the copies. The entry's size is what keeping one copy would free.
+func (m *Model) attachDuplicates(msg duplicatesMsg) {
+	m.findingDupes = false
+	if msg.err != nil {
+		m.message = "Error: " + msg.err.Error()
+		return
+	}
+	m.duplicates = make([]*scanner.CacheEntry, 0, len(msg.groups))
+	for _, g := range msg.groups {
+		copies := len(g.Files)
+		group := &scanner.CacheEntry{
+			Name:        g.Files[0].Name,
+			Path:        g.Files[0].Path,
+			Size:        g.Size * int64(copies-1),
+			AllocSize:   g.Reclaimable(),
+			FileCount:   copies,
+			LastMod:     g.Files[0].LastMod,
+			Description: fmt.Sprintf("%d copies of %s", copies, scanner.FormatSize(g.Size)),
+			Children:    g.Files,
+			Expanded:    true,
+		}
+		for _, f := range g.Files {
+			f.Depth = 1
+			if f.LastMod.After(group.LastMod) {
+				group.LastMod = f.LastMod
+			}
+			group.Ages.Merge(&f.Ages)
+		}
+		m.duplicates = append(m.duplicates, group)
+	}
+	if m.list == listDuplicates {
+		m.setLocation(m.location, nil)
+	}
+}
+
+// toggleDuplicate flips the selection of a copy, or selects every copy of a
+// group but the first. The last unselected copy of a group can't be
+// selected, so cleaning always leaves one.
+func (m *Model) toggleDuplicate(e *scanner.CacheEntry) {
+	for _, group := range m.duplicates {
+		if e == group {
+			if countSelected(group) > 0 {
+				selectTree(group, false)
+			} else {
+				selectExtraCopies(group)
+			}
+			return
+		}
+		for _, c := range group.Children {
+			if c != e {
+				continue
+			}
+			if !c.Selected && countSelected(group) == len(group.Children)-1 {
+				m.message = "Keep at least one copy"
+				return
+			}
+			c.Selected = !c.Selected
+			return
+		}
+	}
+}
+
+// selectExtraCopies selects every copy in group but the first
+func selectExtraCopies(group *scanner.CacheEntry) {
+	for i, c := range group.Children {
+		c.Selected = i > 0
+	}
+}
+
+func countSelected(group *scanner.CacheEntry) int {
+	n := 0
+	for _, c := range group.Children {
+		if c.Selected {
+			n++
+		}
+	}
+	return n
+}
+
+// duplicateSummary describes the duplicate groups for the breadcrumbs
+func (m Model) duplicateSummary() string {
+	if m.findingDupes {
+		return "  " + m.spinner.View() + " looking for duplicates..."
+	}
+	var reclaimable int64
+	for _, group := range m.duplicates {
+		reclaimable += group.AllocSize
+	}
+	return fmt.Sprintf("  %d groups, %s reclaimable", len(m.duplicates), scanner.FormatSize(reclaimable))
+}
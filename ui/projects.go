@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+type artifactsMsg struct {
+	artifacts []scanner.Artifact
+	err       error
+}
+
+// findArtifacts looks for build artifacts in the project roots in the
+// background
+func (m *Model) findArtifacts() tea.Cmd {
+	m.findingBuilds = true
+	opts := m.opts
+	return func() tea.Msg {
+		s, err := opts.newScanner()
+		if err != nil {
+			return artifactsMsg{err: err}
+		}
+		artifacts, err := s.FindArtifacts(context.Background())
+		return artifactsMsg{artifacts: artifacts, err: err}
+	}
+}
+
+// attachArtifacts lists the artifacts found, remembering their projects
+func (m *Model) attachArtifacts(msg artifactsMsg) {
+	m.findingBuilds = false
+	if msg.err != nil {
+		m.message = "Error: " + msg.err.Error()
+		return
+	}
+	m.artifacts = make([]*scanner.CacheEntry, 0, len(msg.artifacts))
+	m.projects = make(map[*scanner.CacheEntry]scanner.Artifact, len(msg.artifacts))
+	for _, a := range msg.artifacts {
+		if a.Size == 0 {
+			continue
+		}
+		m.artifacts = append(m.artifacts, a.CacheEntry)
+		m.projects[a.CacheEntry] = a
+	}
+	if m.list == listArtifacts {
+		m.setLocation(m.location, nil)
+	}
+}
+
+// artifactNotes renders what to know about an artifact before cleaning it:
+// how long its project has sat untouched, and what git says about it
+func (m Model) artifactNotes(a scanner.Artifact) string {
+	var notes string
+	if !a.ProjectModified.IsZero() {
+		notes += dimStyle.Render("  project changed " + a.ProjectModified.Format("Jan 02 2006"))
+	}
+	if a.Blocked != nil {
+		notes += lipgloss.NewStyle().Foreground(colorRed).Render("  ⊘ " + a.Blocked.Error())
+	} else if a.Dirty {
+		notes += lipgloss.NewStyle().Foreground(colorPeach).Render("  ⚠ uncommitted changes in repo")
+	}
+	return notes
+}
+
+// artifactSummary describes the artifacts found for the breadcrumbs
+func (m Model) artifactSummary() string {
+	if m.findingBuilds {
+		return "  " + m.spinner.View() + " searching project roots..."
+	}
+	var total int64
+	projects := make(map[string]bool)
+	for _, e := range m.artifacts {
+		total += m.sizeOf(e)
+		projects[m.projects[e].Project] = true
+	}
+	return fmt.Sprintf("  %d in %d projects, %s", len(m.artifacts), len(projects), scanner.FormatSize(total))
+}
@@ -0,0 +1,435 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// trail is a path through the entry tree, from a target down to one of its
+// descendants. An empty trail is the list of targets itself.
+type trail []*scanner.CacheEntry
+
+// childrenMsg carries the sized children of a directory being opened
+type childrenMsg struct {
+	entry    *scanner.CacheEntry
+	children []*scanner.CacheEntry
+	drill    bool // Navigate into entry once loaded, rather than expand it
+	err      error
+}
+
+// loadChildrenCmd sizes the items inside e in the background
+func loadChildrenCmd(opts Options, e *scanner.CacheEntry, drill bool) tea.Cmd {
+	return func() tea.Msg {
+		s, err := opts.newScanner()
+		if err != nil {
+			return childrenMsg{entry: e, drill: drill, err: err}
+		}
+		children, err := s.ScanChildren(context.Background(), e)
+		return childrenMsg{entry: e, children: children, drill: drill, err: err}
+	}
+}
+
+// canOpen reports whether e has, or may have, children to show
+func canOpen(e *scanner.CacheEntry) bool {
+	if e.IsSymlink || e.Excluded || !e.IsDir {
+		return false
+	}
+	return !e.Listed || len(e.Children) > 0
+}
+
+// pathTo returns the trail from a target in entries down to e, or nil if e
+// isn't in the tree
+func pathTo(entries []*scanner.CacheEntry, e *scanner.CacheEntry) trail {
+	for _, entry := range entries {
+		if entry == e {
+			return trail{entry}
+		}
+		if sub := pathTo(entry.Children, e); sub != nil {
+			return append(trail{entry}, sub...)
+		}
+	}
+	return nil
+}
+
+// viewEntries returns the entries at the top of the list: the targets, the
+// children of the directory navigated into, the largest files or the
+// duplicate groups
+func (m Model) viewEntries() []*scanner.CacheEntry {
+	switch m.list {
+	case listLargest:
+		return m.largest
+	case listDuplicates:
+		return m.duplicates
+	case listArtifacts:
+		return m.artifacts
+	}
+	if len(m.location) == 0 {
+		return m.entries
+	}
+	return m.location[len(m.location)-1].Children
+}
+
+// targetOf returns the target a top-level list entry belongs to
+func (m Model) targetOf(e *scanner.CacheEntry) *scanner.CacheEntry {
+	if m.list != listTree {
+		return targetContaining(m.entries, e.Path)
+	}
+	if len(m.location) == 0 {
+		return e
+	}
+	return m.location[0]
+}
+
+// open expands e in place, or navigates into it with drill, sizing its
+// children first if that hasn't happened yet
+func (m *Model) open(e *scanner.CacheEntry, drill bool) tea.Cmd {
+	if !canOpen(e) || m.list != listTree {
+		return nil
+	}
+	if !e.Listed {
+		if m.loading[e] {
+			return nil
+		}
+		m.loading[e] = true
+		return loadChildrenCmd(m.opts, e, drill)
+	}
+	if drill {
+		m.navigate(pathTo(m.entries, e), nil)
+	} else {
+		e.Expanded = !e.Expanded
+		m.rebuildDisplayList()
+	}
+	return nil
+}
+
+// attachChildren adds freshly sized children to their directory
+func (m *Model) attachChildren(msg childrenMsg) {
+	delete(m.loading, msg.entry)
+	if pathTo(m.entries, msg.entry) == nil {
+		return // The tree was rescanned meanwhile
+	}
+	if msg.err != nil {
+		m.message = "Error: " + msg.err.Error()
+		return
+	}
+	for _, child := range msg.children {
+		// A selected directory stays selected as a whole
+		child.Selected = msg.entry.Selected && !child.Excluded
+	}
+	msg.entry.Children = msg.children
+	msg.entry.Listed = true
+	m.sortTree(msg.entry.Children)
+	if msg.drill {
+		m.navigate(pathTo(m.entries, msg.entry), nil)
+		return
+	}
+	msg.entry.Expanded = true
+	m.rebuildDisplayList()
+}
+
+// navigate moves the list to to, remembering where it was for back. The
+// cursor lands on focus if it's in the new list.
+func (m *Model) navigate(to trail, focus *scanner.CacheEntry) {
+	m.back = append(m.back, m.location)
+	m.forward = nil
+	m.setLocation(to, focus)
+}
+
+// goBack returns to the previous location, if any
+func (m *Model) goBack() {
+	if len(m.back) == 0 {
+		return
+	}
+	prev := m.back[len(m.back)-1]
+	m.back = m.back[:len(m.back)-1]
+	m.forward = append(m.forward, m.location)
+	m.setLocation(prev, m.current())
+}
+
+// goForward undoes the last goBack
+func (m *Model) goForward() {
+	if len(m.forward) == 0 {
+		return
+	}
+	next := m.forward[len(m.forward)-1]
+	m.forward = m.forward[:len(m.forward)-1]
+	m.back = append(m.back, m.location)
+	m.setLocation(next, nil)
+}
+
+// goUp navigates to the parent of the current location, or back to the
+// tree from another list
+func (m *Model) goUp() {
+	if m.list != listTree {
+		m.setList(listTree)
+		return
+	}
+	if len(m.location) == 0 {
+		return
+	}
+	m.navigate(m.location[:len(m.location)-1], m.current())
+}
+
+// setList switches the list to mode, or back to the tree if it's showing
+// mode already
+func (m *Model) setList(mode listMode) {
+	if m.list == mode {
+		mode = listTree
+	}
+	m.list = mode
+	m.setLocation(m.location, nil)
+}
+
+// current returns the directory the list is showing, nil for the targets
+func (m Model) current() *scanner.CacheEntry {
+	if len(m.location) == 0 {
+		return nil
+	}
+	return m.location[len(m.location)-1]
+}
+
+func (m *Model) setLocation(to trail, focus *scanner.CacheEntry) {
+	m.location = to
+	m.rebuildDisplayList()
+	m.cursor = 0
+	for i, de := range m.displayList {
+		if de.entry == focus {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// resetLocation goes back to the target list, for when the tree is replaced
+func (m *Model) resetLocation() {
+	m.location = nil
+	m.back = nil
+	m.forward = nil
+	m.loading = make(map[*scanner.CacheEntry]bool)
+}
+
+// updateTarget copies the totals of a freshly sized target onto its listed
+// entry, keeping what the user did with it meanwhile
+func (m *Model) updateTarget(sized *scanner.CacheEntry) {
+	for _, e := range m.entries {
+		if e.Path != sized.Path {
+			continue
+		}
+		children, listed := e.Children, e.Listed
+		selected, expanded := e.Selected, e.Expanded
+		*e = *sized
+		if listed {
+			e.Children, e.Listed = children, true
+		}
+		e.Selected, e.Expanded = selected, expanded
+		return
+	}
+}
+
+// mergeTargets applies a finished scan to the listed targets, adds the ones
+// that weren't listed, like Docker, and drops the ones it left out: empty,
+// or never sized because the scan was cancelled
+func (m *Model) mergeTargets(result []*scanner.CacheEntry) {
+	sized := make(map[string]bool, len(result))
+	for _, e := range result {
+		m.updateTarget(e)
+		sized[e.Path] = true
+	}
+	var kept []*scanner.CacheEntry
+	listed := make(map[string]bool, len(m.entries))
+	for _, e := range m.entries {
+		listed[e.Path] = true
+		if sized[e.Path] {
+			kept = append(kept, e)
+		}
+	}
+	for _, e := range result {
+		if !listed[e.Path] {
+			kept = append(kept, e)
+		}
+	}
+	if len(m.location) > 0 && !sized[m.location[0].Path] {
+		m.resetLocation()
+	}
+	m.entries = kept
+}
+
+// refreshList re-sorts and redraws the list with fresh sizes, keeping the
+// cursor on the same entry
+func (m *Model) refreshList() {
+	var focus *scanner.CacheEntry
+	if m.cursor < len(m.displayList) {
+		focus = m.displayList[m.cursor].entry
+	}
+	m.sortEntries()
+	m.rebuildDisplayList()
+	m.cursor = 0
+	for i, de := range m.displayList {
+		if de.entry == focus {
+			m.cursor = i
+			break
+		}
+	}
+	m.updateSelectedSize()
+}
+
+// breadcrumbs renders the current location
+func (m Model) breadcrumbs() string {
+	switch m.list {
+	case listLargest:
+		return "  " + headerStyle.Render(fmt.Sprintf("Largest files (top %d)", scanner.LargestFiles))
+	case listDuplicates:
+		return "  " + headerStyle.Render("Duplicate files") + dimStyle.Render(m.duplicateSummary())
+	case listArtifacts:
+		return "  " + headerStyle.Render("Build artifacts") + dimStyle.Render(m.artifactSummary())
+	}
+	parts := []string{"All targets"}
+	for i, e := range m.location {
+		name := e.Name
+		if i == 0 && e.Description != "" {
+			name = e.Description
+		}
+		parts = append(parts, name)
+	}
+	last := len(parts) - 1
+	parts[last] = headerStyle.Render(parts[last])
+	return "  " + strings.Join(parts, dimStyle.Render(" › "))
+}
+
+// setSelected selects or deselects e along with every child loaded so far.
+// Deselecting also deselects e's ancestors, which would otherwise still
+// count e as part of them.
+func (m *Model) setSelected(e *scanner.CacheEntry, selected bool) {
+	if e.Excluded || e.Pending {
+		return // Excluded items can't be cleaned, unsized ones not yet
+	}
+	if mod, ok := scanner.ModuleFor(e.Path).(scanner.WholeCleaner); ok && mod.CleansWhole() {
+		if t := pathTo(m.entries, e); len(t) > 0 {
+			e = t[0]
+		}
+	}
+	selectTree(e, selected)
+	if !selected {
+		for _, ancestor := range pathTo(m.entries, e) {
+			ancestor.Selected = false
+		}
+	}
+}
+
+func selectTree(e *scanner.CacheEntry, selected bool) {
+	e.Selected = selected && !e.Excluded
+	for _, child := range e.Children {
+		selectTree(child, selected)
+	}
+}
+
+// walkSelected calls fn for every entry to clean along with its target. A
+// selected entry stands for everything inside it, so its children are never
+// visited.
+func walkSelected(entries []*scanner.CacheEntry, target *scanner.CacheEntry, fn func(e, target *scanner.CacheEntry)) {
+	for _, e := range entries {
+		t := target
+		if t == nil {
+			t = e
+		}
+		if e.Selected {
+			fn(e, t)
+			continue
+		}
+		walkSelected(e.Children, t, fn)
+	}
+}
+
+// eachSelected calls fn for every entry to clean along with its target: the
+// selected parts of the tree, then the selected largest files, duplicate
+// copies and build artifacts. An entry inside another selected one is
+// cleaned with it, so it's left out.
+func (m Model) eachSelected(fn func(e, target *scanner.CacheEntry)) {
+	var picks []pick
+	walkSelected(m.entries, nil, func(e, target *scanner.CacheEntry) {
+		picks = append(picks, pick{e, target})
+	})
+	files := slices.Clone(m.largest)
+	for _, group := range m.duplicates {
+		files = append(files, group.Children...)
+	}
+	for _, file := range files {
+		if file.Selected {
+			picks = append(picks, pick{file, targetContaining(m.entries, file.Path)})
+		}
+	}
+	for _, a := range m.artifacts {
+		if a.Selected {
+			picks = append(picks, pick{a, a}) // Artifacts carry their own risk note
+		}
+	}
+
+	for i, p := range picks {
+		if !insideAnother(picks, i) {
+			fn(p.e, p.target)
+		}
+	}
+}
+
+// pick is a selected entry along with its target
+type pick struct {
+	e, target *scanner.CacheEntry
+}
+
+// insideAnother reports whether picks[i] is cleaned along with another
+// pick. Of two picks for the same place, the first one stays.
+func insideAnother(picks []pick, i int) bool {
+	e := picks[i].e
+	for j, other := range picks {
+		if j != i && includes(other.e, e) && (j < i || !includes(e, other.e)) {
+			return true
+		}
+	}
+	return false
+}
+
+// includes reports whether cleaning e also cleans inner, which lies below
+// e's path or below the directory e Covers
+func includes(e, inner *scanner.CacheEntry) bool {
+	for _, path := range []string{inner.Path, inner.Covers} {
+		if path == "" {
+			continue
+		}
+		if scanner.Within(path, e.Path) || (e.Covers != "" && scanner.Within(path, e.Covers)) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetContaining returns the target path lies in. Targets can nest, so the
+// innermost one wins.
+func targetContaining(targets []*scanner.CacheEntry, path string) *scanner.CacheEntry {
+	var best *scanner.CacheEntry
+	for _, t := range targets {
+		if scanner.Within(path, t.Path) && (best == nil || len(t.Path) > len(best.Path)) {
+			best = t
+		}
+	}
+	if best == nil {
+		return &scanner.CacheEntry{Path: path} // Its target is gone; clean without a risk note
+	}
+	return best
+}
+
+// sortTree orders entries, and every child list below them, by size in the
+// current display mode
+func (m *Model) sortTree(entries []*scanner.CacheEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return m.sizeOf(entries[i]) > m.sizeOf(entries[j])
+	})
+	for _, e := range entries {
+		m.sortTree(e.Children)
+	}
+}
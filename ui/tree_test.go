@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/han-nwin/dusty/scanner"
+)
+
+func entry(path string, size int64, selected bool, children ...*scanner.CacheEntry) *scanner.CacheEntry {
+	return &scanner.CacheEntry{Path: path, Size: size, AllocSize: size, Selected: selected, Children: children}
+}
+
+func TestSelectionCountsNestedEntriesOnce(t *testing.T) {
+	m := NewModel(Options{})
+	cache := entry("/h/.cache", 1000, true, entry("/h/.cache/pip", 300, true))
+	pip := entry("/h/.cache/pip", 300, true) // The same place as a target of its own
+	brew := entry("tool:brew", 200, true)
+	brew.Covers = "/h/.cache/brew"
+	logs := entry("/h/logs", 50, true)
+	m.entries = []*scanner.CacheEntry{cache, pip, brew, logs, entry("/h/idle", 5000, false)}
+
+	big := entry("/h/.cache/big", 100, true)
+	loose := entry("/h/other/f", 10, true)
+	m.largest = []*scanner.CacheEntry{big, loose}
+	m.duplicates = []*scanner.CacheEntry{entry("dup", 20, false,
+		entry("/h/other/f", 10, true), // Also among the largest files
+		entry("/h/logs/copy", 10, true),
+	)}
+	m.artifacts = []*scanner.CacheEntry{entry("/h/src/node_modules", 70, true), entry("/h/src/target", 90, false)}
+
+	m.updateSelectedSize()
+	if want := int64(1000 + 50 + 10 + 70); m.selectedSize != want || m.selectedAlloc != want {
+		t.Errorf("selected = %d, %d on disk; want %d", m.selectedSize, m.selectedAlloc, want)
+	}
+	var paths []string
+	for _, e := range m.selectedEntries() {
+		paths = append(paths, e.Path)
+	}
+	want := []string{"/h/.cache", "/h/logs", "/h/other/f", "/h/src/node_modules"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("cleaning %q, want %q", paths, want)
+	}
+}
+
+func TestSelectionKeepsDisjointEntries(t *testing.T) {
+	m := NewModel(Options{})
+	// A shared name prefix isn't nesting
+	m.entries = []*scanner.CacheEntry{entry("/h/cache", 10, true), entry("/h/cache2", 20, true)}
+	brew := entry("tool:brew", 40, true)
+	brew.Covers = "/h/brew"
+	m.entries = append(m.entries, brew)
+
+	m.updateSelectedSize()
+	if m.selectedSize != 70 || len(m.selectedEntries()) != 3 {
+		t.Errorf("selected %d bytes in %d entries, want 70 in 3", m.selectedSize, len(m.selectedEntries()))
+	}
+}
+
+func TestSelectionTotalsFollowSizeMode(t *testing.T) {
+	sparse := entry("/h/.cache/vm", 8000, true)
+	sparse.AllocSize = 2000
+	linked := entry("/h/.cache/store", 1000, true)
+	linked.AllocSize, linked.SharedSize = 4000, 1500 // Small files, some linked from elsewhere
+	m := listModel(sparse, linked, entry("/h/idle", 300, false))
+
+	if m.selectedSize != 9000 || m.selectedAlloc != 4500 {
+		t.Errorf("apparent mode: selected %d, %d freed; want 9000, 4500", m.selectedSize, m.selectedAlloc)
+	}
+	m = press(m, "d")
+	if !m.showAlloc || m.selectedSize != 6000 || m.selectedAlloc != 4500 {
+		t.Errorf("on-disk mode: selected %d, %d freed; want 6000, 4500", m.selectedSize, m.selectedAlloc)
+	}
+	// Sorted by on-disk size now, so the cursor is on the linked store
+	m = press(m, " ")
+	if linked.Selected || m.selectedSize != 2000 || m.selectedAlloc != 2000 {
+		t.Errorf("deselected store: selected %d, %d freed; want the vm's 2000", m.selectedSize, m.selectedAlloc)
+	}
+}
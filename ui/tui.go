@@ -1,9 +1,11 @@
 package ui
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/han-nwin/dusty/cleaner"
 	"github.com/han-nwin/dusty/scanner"
 )
 
@@ -105,12 +108,26 @@ var (
 			BorderForeground(colorSurface2).
 			Padding(1, 2)
 
+	excludedStyle = lipgloss.NewStyle().
+			Foreground(colorSurface2).
+			Strikethrough(true)
+
 	expandIcon   = lipgloss.NewStyle().Foreground(colorPeach).Render("▶")
 	collapseIcon = lipgloss.NewStyle().Foreground(colorPeach).Render("▼")
 )
 
 type viewState int
 
+// listMode is what the list view shows
+type listMode int
+
+const (
+	listTree       listMode = iota // The targets and what's inside them
+	listLargest                    // The largest files
+	listDuplicates                 // Groups of identical files
+	listArtifacts                  // Build artifacts in project roots
+)
+
 const (
 	viewList viewState = iota
 	viewScanning
@@ -121,30 +138,109 @@ const (
 )
 
 // Messages
+type scanStartedMsg struct {
+	ch     <-chan tea.Msg // Progress and completion messages of the new scan
+	cancel context.CancelFunc
+}
+
+// scanEventMsg is a message from a running scan, tagged with the channel it
+// came on so messages from a superseded scan can be told apart
+type scanEventMsg struct {
+	ch  <-chan tea.Msg
+	msg tea.Msg
+}
+
+// scanListedMsg carries placeholders for the targets about to be sized
+type scanListedMsg []*scanner.CacheEntry
+
+type scanProgressMsg scanner.Progress
+
 type scanCompleteMsg struct {
 	result *scanner.ScanResult
 	err    error
 }
 
+type watchStartedMsg struct {
+	ch     <-chan tea.Msg // Changes, then the reason watching stopped
+	cancel context.CancelFunc
+}
+
+// watchEventMsg is a message from the running watch, tagged like
+// scanEventMsg
+type watchEventMsg struct {
+	ch  <-chan tea.Msg
+	msg tea.Msg
+}
+
+type watchChangesMsg []scanner.Change
+
+type watchStoppedMsg struct {
+	err error
+}
+
+type ageMatchMsg struct {
+	match scanner.AgeMatch
+	err   error
+}
+
 type cleanCompleteMsg struct {
-	cleaned int64
-	err     error
+	report *cleaner.Report
+	err    error
 }
 
 // displayEntry is a flattened entry for display
 type displayEntry struct {
-	entry    *scanner.CacheEntry
-	isChild  bool
-	parentIdx int
+	entry  *scanner.CacheEntry
+	depth  int                 // Levels below the top of the list
+	target *scanner.CacheEntry // Target the entry belongs to
+}
+
+// Options configures how the TUI scans and cleans
+type Options struct {
+	OneFileSystem bool     // Don't cross filesystem boundaries below a target
+	Profile       string   // Profile to scan; empty means Full
+	OlderThanDays int      // Clean only files older than this many days; 0 cleans everything
+	AccessTime    bool     // Judge age by last access instead of last modification
+	NoCache       bool     // Read every directory, ignoring and not updating the scan cache
+	Watch         bool     // Follow changes to the targets after each scan
+	Projects      []string // Directories to search for build artifacts, besides the config file's
+}
+
+// ageFilter returns the age cutoff for cleaning, inactive when unset
+func (o Options) ageFilter() scanner.AgeFilter {
+	f := scanner.AgeFilter{OlderThan: time.Duration(o.OlderThanDays) * 24 * time.Hour}
+	if o.AccessTime {
+		f.Basis = scanner.ByAccessTime
+	}
+	return f
+}
+
+// ageSteps are the cutoffs the o key cycles through, in days
+var ageSteps = []int{0, 7, 30, 90, 180}
+
+// newScanner creates a scanner configured by o
+func (o Options) newScanner() (*scanner.Scanner, error) {
+	s, err := scanner.NewScanner()
+	if err != nil {
+		return nil, err
+	}
+	s.OneFileSystem = o.OneFileSystem
+	s.Profile = o.Profile
+	s.Projects = o.Projects
+	return s, nil
 }
 
 // Model represents the TUI state
 type Model struct {
+	opts          Options
 	entries       []*scanner.CacheEntry
 	displayList   []displayEntry
 	cursor        int
 	totalSize     int64
+	totalAlloc    int64
 	selectedSize  int64
+	selectedAlloc int64 // On-disk bytes of the selection, what cleaning frees
+	showAlloc     bool  // Show on-disk sizes instead of apparent sizes
 	scanTime      time.Duration
 	state         viewState
 	spinner       spinner.Model
@@ -154,10 +250,40 @@ type Model struct {
 	height        int
 	message       string
 	err           error
-	confirmAction string // "delete" or "trash"
+	confirmAction string            // "delete" or "trash"
+	ageMatch      *scanner.AgeMatch // Files the age cutoff selects; nil while counting
+	list          listMode
+	largest       []*scanner.CacheEntry
+	duplicates    []*scanner.CacheEntry // One entry per group, the copies as children; nil until found
+	findingDupes  bool
+	artifacts     []*scanner.CacheEntry // Nil until found
+	projects      map[*scanner.CacheEntry]scanner.Artifact
+	findingBuilds bool
+
+	// Tree navigation
+	location trail                        // Directory the list shows; empty for the targets
+	back     []trail                      // Earlier locations, most recent last
+	forward  []trail                      // Locations left with back, most recent last
+	loading  map[*scanner.CacheEntry]bool // Directories whose children are being sized
+
+	// Live scan state
+	scanCh       <-chan tea.Msg
+	cancelScan   context.CancelFunc
+	scanProgress scanner.Progress
+	scanRows     []*scanner.CacheEntry // Targets finished so far, in arrival order
+
+	// Live watch state
+	watchCh     <-chan tea.Msg
+	cancelWatch context.CancelFunc
 }
 
+// InitialModel returns a model with default options
 func InitialModel() Model {
+	return NewModel(Options{})
+}
+
+// NewModel returns a model configured by opts
+func NewModel(opts Options) Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(colorMauve)
@@ -168,34 +294,96 @@ func InitialModel() Model {
 	ti.Width = 30
 
 	return Model{
+		opts:        opts,
 		state:       viewScanning,
 		spinner:     s,
 		filterInput: ti,
+		loading:     make(map[*scanner.CacheEntry]bool),
 		width:       80,
 		height:      24,
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, scanCmd())
+	return tea.Batch(m.spinner.Tick, scanCmd(m.opts))
 }
 
-func scanCmd() tea.Cmd {
+// scanCmd starts a scan in the background. Its progress and result arrive
+// on the channel carried by scanStartedMsg.
+func scanCmd(opts Options) tea.Cmd {
 	return func() tea.Msg {
-		s, err := scanner.NewScanner()
-		if err != nil {
-			return scanCompleteMsg{err: err}
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan tea.Msg, 64)
+		go runScan(ctx, opts, ch)
+		return scanStartedMsg{ch: ch, cancel: cancel}
+	}
+}
+
+func runScan(ctx context.Context, opts Options, ch chan<- tea.Msg) {
+	defer close(ch)
+	s, err := opts.newScanner()
+	if err != nil {
+		ch <- scanCompleteMsg{err: err}
+		return
+	}
+	// Targets are sized whole and listed right away; their contents are
+	// sized when opened
+	s.Shallow = true
+	if !opts.NoCache {
+		s.Cache = s.LoadCache()
+	}
+	ch <- scanListedMsg(s.ListTargets())
+	s.OnProgress = func(p scanner.Progress) {
+		if p.Done != nil {
+			ch <- scanProgressMsg(p)
+			return
+		}
+		// Drop periodic updates if the UI is behind; the next one catches up
+		select {
+		case ch <- scanProgressMsg(p):
+		default:
 		}
-		result, err := s.Scan()
-		return scanCompleteMsg{result: result, err: err}
 	}
+	result, err := s.Scan(ctx)
+	ch <- scanCompleteMsg{result: result, err: err}
+}
+
+// waitForScan delivers the next message from a running scan
+func waitForScan(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return scanEventMsg{ch: ch, msg: msg}
+	}
+}
+
+// startScan cancels any scan in flight and starts a new one
+func (m *Model) startScan() tea.Cmd {
+	m.stopScan()
+	m.stopWatch()
+	m.state = viewScanning
+	m.message = ""
+	m.cursor = 0
+	return tea.Batch(m.spinner.Tick, scanCmd(m.opts))
+}
+
+// stopScan cancels the scan in flight, if any. Its remaining messages are
+// drained and ignored.
+func (m *Model) stopScan() {
+	if m.cancelScan != nil {
+		m.cancelScan()
+		m.cancelScan = nil
+	}
+	m.scanCh = nil
 }
 
 func (m *Model) rebuildDisplayList() {
 	m.displayList = nil
 	filterLower := strings.ToLower(m.filter)
 
-	for i, entry := range m.entries {
+	for _, entry := range m.viewEntries() {
 		// Check if entry matches filter
 		if m.filter != "" {
 			if !strings.Contains(strings.ToLower(entry.Name), filterLower) &&
@@ -204,27 +392,26 @@ func (m *Model) rebuildDisplayList() {
 			}
 		}
 
-		m.displayList = append(m.displayList, displayEntry{
-			entry:    entry,
-			isChild:  false,
-			parentIdx: i,
-		})
-
-		// Add children if expanded
-		if entry.Expanded && entry.IsParent {
-			for _, child := range entry.Children {
-				if m.filter != "" {
-					if !strings.Contains(strings.ToLower(child.Name), filterLower) {
-						continue
-					}
-				}
-				m.displayList = append(m.displayList, displayEntry{
-					entry:    child,
-					isChild:  true,
-					parentIdx: i,
-				})
+		target := m.targetOf(entry)
+		m.displayList = append(m.displayList, displayEntry{entry: entry, target: target})
+		m.appendExpanded(entry, target, 1, filterLower)
+	}
+}
+
+// appendExpanded adds the children of an expanded entry, and theirs, to the
+// display list
+func (m *Model) appendExpanded(e, target *scanner.CacheEntry, depth int, filterLower string) {
+	if !e.Expanded {
+		return
+	}
+	for _, child := range e.Children {
+		if m.filter != "" {
+			if !strings.Contains(strings.ToLower(child.Name), filterLower) {
+				continue
 			}
 		}
+		m.displayList = append(m.displayList, displayEntry{entry: child, depth: depth, target: target})
+		m.appendExpanded(child, target, depth+1, filterLower)
 	}
 }
 
@@ -240,28 +427,66 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
 
-	case scanCompleteMsg:
-		if msg.err != nil {
-			m.err = msg.err
-			m.state = viewList
-			return m, nil
+	case scanStartedMsg:
+		// A newer scan supersedes whatever is still running
+		m.stopScan()
+		m.scanCh = msg.ch
+		m.cancelScan = msg.cancel
+		m.scanProgress = scanner.Progress{}
+		m.scanRows = nil
+		return m, waitForScan(m.scanCh)
+
+	case scanEventMsg:
+		if msg.ch != m.scanCh {
+			// Drain a superseded scan so its goroutine can exit
+			return m, waitForScan(msg.ch)
 		}
-		m.entries = msg.result.Entries
-		m.totalSize = msg.result.TotalSize
-		m.scanTime = msg.result.ScanTime
-		m.state = viewList
-		m.rebuildDisplayList()
+		return m.handleScanEvent(msg.msg)
+
+	case watchStartedMsg:
+		m.stopWatch()
+		m.watchCh = msg.ch
+		m.cancelWatch = msg.cancel
+		return m, waitForWatch(m.watchCh)
+
+	case watchEventMsg:
+		if msg.ch != m.watchCh {
+			return m, waitForWatch(msg.ch)
+		}
+		return m.handleWatchEvent(msg.msg)
+
+	case childrenMsg:
+		m.attachChildren(msg)
 		m.updateSelectedSize()
 		return m, nil
 
+	case duplicatesMsg:
+		m.attachDuplicates(msg)
+		return m, nil
+
+	case artifactsMsg:
+		m.attachArtifacts(msg)
+		return m, nil
+
+	case ageMatchMsg:
+		if msg.err != nil {
+			m.message = fmt.Sprintf("Error: %v", msg.err)
+		}
+		m.ageMatch = &msg.match
+		return m, nil
+
 	case cleanCompleteMsg:
+		cmd := m.startScan()
 		if msg.err != nil {
 			m.message = fmt.Sprintf("Error: %v", msg.err)
+		} else if failed := msg.report.Failed(); len(failed) > 0 {
+			m.message = fmt.Sprintf("%s, but %d of %d failed: %v",
+				cleanedText(msg.report), len(failed), len(msg.report.Results), failed[0].Err)
 		} else {
-			m.message = fmt.Sprintf("Cleaned %s!", scanner.FormatSize(msg.cleaned))
+			m.message = cleanedText(msg.report) + "!"
 		}
 		m.state = viewList
-		return m, scanCmd()
+		return m, cmd
 
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
@@ -270,7 +495,79 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m Model) handleScanEvent(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case scanListedMsg:
+		if m.state == viewScanning {
+			m.state = viewList
+		}
+		m.err = nil
+		m.entries = msg
+		m.largest = nil
+		m.duplicates = nil
+		m.totalSize = 0
+		m.totalAlloc = 0
+		m.resetLocation()
+		m.refreshList()
+		return m, waitForScan(m.scanCh)
+
+	case scanProgressMsg:
+		m.scanProgress = scanner.Progress(msg)
+		if msg.Done != nil && msg.Done.Size > 0 {
+			m.scanRows = append(m.scanRows, msg.Done)
+			m.updateTarget(msg.Done)
+			m.totalSize += msg.Done.Size
+			m.totalAlloc += msg.Done.AllocSize
+			m.refreshList()
+		}
+		return m, waitForScan(m.scanCh)
+
+	case scanCompleteMsg:
+		m.scanCh = nil
+		if m.cancelScan != nil {
+			m.cancelScan()
+			m.cancelScan = nil
+		}
+		m.state = viewList
+		if msg.err != nil && (msg.result == nil || !errors.Is(msg.err, context.Canceled)) {
+			m.err = msg.err
+			return m, nil
+		}
+		if msg.err != nil {
+			m.message = "Scan cancelled - showing partial results"
+		}
+		m.err = errors.Join(msg.result.Errors...)
+		m.mergeTargets(msg.result.Entries)
+		m.largest = msg.result.Largest
+		m.totalSize = msg.result.TotalSize
+		m.totalAlloc = msg.result.TotalAllocSize
+		m.scanTime = msg.result.ScanTime
+		m.refreshList()
+		var cmds []tea.Cmd
+		switch m.list {
+		case listDuplicates:
+			// The old groups may have been cleaned up
+			cmds = append(cmds, m.findDuplicates())
+		case listArtifacts:
+			cmds = append(cmds, m.findArtifacts())
+		}
+		if m.opts.Watch {
+			cmds = append(cmds, m.startWatch())
+		}
+		return m, tea.Batch(cmds...)
+	}
+	return m, nil
+}
+
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Esc aborts a running scan; the partial result arrives as usual
+	if m.state == viewScanning && msg.String() == "esc" {
+		if m.cancelScan != nil {
+			m.cancelScan()
+		}
+		return m, nil
+	}
+
 	// Handle filter input mode
 	if m.state == viewFilter {
 		switch msg.String() {
@@ -295,6 +592,9 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.state == viewConfirm {
 		switch msg.String() {
 		case "y", "Y":
+			if m.opts.ageFilter().Active() && m.ageMatch == nil {
+				return m, nil // Still counting
+			}
 			m.state = viewCleaning
 			return m, m.cleanCmd()
 		case "n", "N", "esc":
@@ -313,6 +613,8 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Normal list navigation
 	switch msg.String() {
 	case "ctrl+c", "q":
+		m.stopScan()
+		m.stopWatch()
 		return m, tea.Quit
 
 	case "up", "k":
@@ -325,36 +627,45 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.cursor++
 		}
 
-	case "enter", "l":
-		// Toggle expand/collapse for parent items
+	case "enter":
+		// Toggle expand/collapse in place
 		if len(m.displayList) > 0 && m.cursor < len(m.displayList) {
-			de := m.displayList[m.cursor]
-			if !de.isChild && de.entry.IsParent {
-				de.entry.Expanded = !de.entry.Expanded
-				m.rebuildDisplayList()
-			}
+			return m, m.open(m.displayList[m.cursor].entry, false)
+		}
+
+	case "l", "right":
+		// Navigate into the directory
+		if len(m.displayList) > 0 && m.cursor < len(m.displayList) {
+			return m, m.open(m.displayList[m.cursor].entry, true)
 		}
 
+	case "h", "left", "backspace":
+		m.goUp()
+
+	case "[":
+		m.goBack()
+
+	case "]":
+		m.goForward()
+
 	case " ":
 		if len(m.displayList) > 0 && m.cursor < len(m.displayList) {
-			de := m.displayList[m.cursor]
-			de.entry.Selected = !de.entry.Selected
-
-			// If selecting a parent, select/deselect all children too
-			if !de.isChild && de.entry.IsParent {
-				for _, child := range de.entry.Children {
-					child.Selected = de.entry.Selected
-				}
+			e := m.displayList[m.cursor].entry
+			if m.list == listDuplicates {
+				m.toggleDuplicate(e)
+			} else {
+				m.setSelected(e, !e.Selected)
 			}
 			m.updateSelectedSize()
 		}
 
 	case "a":
-		// Select all
-		for _, entry := range m.entries {
-			entry.Selected = true
-			for _, child := range entry.Children {
-				child.Selected = true
+		// Select everything in the current list, or every extra copy
+		for _, entry := range m.viewEntries() {
+			if m.list == listDuplicates {
+				selectExtraCopies(entry)
+			} else {
+				m.setSelected(entry, true)
 			}
 		}
 		m.updateSelectedSize()
@@ -362,32 +673,132 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "A":
 		// Deselect all
 		for _, entry := range m.entries {
-			entry.Selected = false
-			for _, child := range entry.Children {
-				child.Selected = false
-			}
+			selectTree(entry, false)
+		}
+		for _, file := range m.largest {
+			file.Selected = false
+		}
+		for _, group := range m.duplicates {
+			selectTree(group, false)
+		}
+		for _, a := range m.artifacts {
+			a.Selected = false
 		}
 		m.updateSelectedSize()
 
+	case "f":
+		// Switch between the tree and the largest files
+		if m.list != listLargest && m.scanCh != nil {
+			m.message = "The largest files are listed once sizing is done"
+			break
+		}
+		m.setList(listLargest)
+
+	case "D":
+		// Switch between the tree and duplicate files
+		if m.list != listDuplicates && m.duplicates == nil && !m.findingDupes {
+			m.setList(listDuplicates)
+			return m, m.findDuplicates()
+		}
+		m.setList(listDuplicates)
+
+	case "P":
+		// Switch between the tree and build artifacts in project roots
+		if m.list != listArtifacts && m.artifacts == nil && !m.findingBuilds {
+			s, err := m.opts.newScanner()
+			if err != nil {
+				m.err = err
+				break
+			}
+			if len(s.ProjectRoots()) == 0 {
+				m.message = `No project roots: add "projects" to the config file or run with -projects`
+				break
+			}
+			m.setList(listArtifacts)
+			return m, m.findArtifacts()
+		}
+		m.setList(listArtifacts)
+
 	case "c":
 		// Clean (permanent delete)
 		if m.selectedSize > 0 {
 			m.confirmAction = "delete"
 			m.state = viewConfirm
+			if m.opts.ageFilter().Active() {
+				m.ageMatch = nil
+				return m, m.ageMatchCmd()
+			}
 		}
 
 	case "t":
 		// Move to trash
 		if m.selectedSize > 0 {
+			if m.opts.ageFilter().Active() {
+				m.message = "Age-based cleaning deletes files one by one; use c, or o to turn it off"
+				break
+			}
+			if name, ok := m.untrashable(); ok {
+				m.message = fmt.Sprintf("%s can't be moved to the Trash, only cleaned for good; use c, or deselect it", name)
+				break
+			}
 			m.confirmAction = "trash"
 			m.state = viewConfirm
 		}
 
-	case "r", "R":
-		m.state = viewScanning
-		m.message = ""
-		m.cursor = 0
-		return m, tea.Batch(m.spinner.Tick, scanCmd())
+	case "o":
+		// Cycle the age cutoff for partial cleaning
+		for i, days := range ageSteps {
+			if days == m.opts.OlderThanDays {
+				m.opts.OlderThanDays = ageSteps[(i+1)%len(ageSteps)]
+				break
+			}
+		}
+
+	case "O":
+		m.opts.AccessTime = !m.opts.AccessTime
+
+	case "r":
+		return m, m.startScan()
+
+	case "R":
+		// Forget cached directory sizes and read everything again
+		s, err := m.opts.newScanner()
+		if err == nil {
+			err = s.ClearCache()
+		}
+		if err != nil {
+			m.err = err
+			break
+		}
+		return m, m.startScan()
+
+	case "d":
+		// Toggle apparent / on-disk sizes
+		m.showAlloc = !m.showAlloc
+		m.sortEntries()
+		m.rebuildDisplayList()
+		m.updateSelectedSize()
+
+	case "w":
+		// Toggle following changes on disk
+		m.opts.Watch = !m.opts.Watch
+		if !m.opts.Watch {
+			m.stopWatch()
+			m.message = "Stopped watching"
+		} else if m.scanCh == nil {
+			m.message = "Watching targets for changes"
+			return m, m.startWatch()
+		}
+
+	case "p":
+		// Switch to the next profile and rescan
+		s, err := m.opts.newScanner()
+		if err != nil {
+			m.err = err
+			break
+		}
+		m.opts.Profile = nextProfile(s.Profiles(), m.opts.Profile)
+		return m, m.startScan()
 
 	case "/":
 		m.state = viewFilter
@@ -403,68 +814,170 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.filterInput.SetValue("")
 			m.rebuildDisplayList()
 			m.cursor = 0
+		} else if m.cancelScan != nil {
+			// Stop sizing; the targets done so far stay
+			m.cancelScan()
 		}
 	}
 
 	return m, nil
 }
 
+// sizeOf returns the size of e in the current display mode
+func (m Model) sizeOf(e *scanner.CacheEntry) int64 {
+	if m.showAlloc {
+		return e.AllocSize
+	}
+	return e.Size
+}
+
+// freedBy returns the on-disk bytes deleting e gives back, leaving out data
+// that hard links elsewhere keep alive
+func freedBy(e *scanner.CacheEntry) int64 {
+	return e.AllocSize - e.SharedSize
+}
+
+// sortEntries orders entries, children and the largest files by size in the
+// current display mode
+func (m *Model) sortEntries() {
+	m.sortTree(m.entries)
+	m.sortTree(m.largest)
+	m.sortTree(m.duplicates)
+	m.sortTree(m.artifacts)
+}
+
+// nextProfile returns the name of the profile after current, wrapping around
+func nextProfile(profiles []scanner.Profile, current string) string {
+	if current == "" {
+		current = scanner.ProfileFull
+	}
+	for i, p := range profiles {
+		if strings.EqualFold(p.Name, current) {
+			return profiles[(i+1)%len(profiles)].Name
+		}
+	}
+	return scanner.ProfileFull
+}
+
+// updateSelectedSize totals the selection. Entries inside a selected
+// directory are part of it, so they're never counted twice.
 func (m *Model) updateSelectedSize() {
 	m.selectedSize = 0
-	for _, entry := range m.entries {
-		if entry.Selected {
-			m.selectedSize += entry.Size
-		} else {
-			// Check children
-			for _, child := range entry.Children {
-				if child.Selected {
-					m.selectedSize += child.Size
-				}
+	m.selectedAlloc = 0
+	m.eachSelected(func(e, _ *scanner.CacheEntry) {
+		m.selectedSize += m.sizeOf(e)
+		m.selectedAlloc += freedBy(e)
+	})
+}
+
+// selectedEntries returns what to clean: the topmost selected entries, each
+// standing for everything inside it
+func (m Model) selectedEntries() []*scanner.CacheEntry {
+	var selected []*scanner.CacheEntry
+	m.eachSelected(func(e, _ *scanner.CacheEntry) {
+		selected = append(selected, e)
+	})
+	return selected
+}
+
+// ageMatchCmd counts the selected files the age cutoff would remove
+func (m Model) ageMatchCmd() tea.Cmd {
+	opts := m.opts
+	selected := m.selectedEntries()
+	return func() tea.Msg {
+		s, err := opts.newScanner()
+		if err != nil {
+			return ageMatchMsg{err: err}
+		}
+		var total scanner.AgeMatch
+		for _, g := range byModule(selected) {
+			// Modules that can't tell, like package managers, count nothing
+			matcher, ok := g.module.(scanner.AgeMatcher)
+			if !ok {
+				continue
+			}
+			match, err := matcher.MatchOlderThan(context.Background(), s, g.entries, opts.ageFilter())
+			total.Files += match.Files
+			total.Size += match.Size
+			total.AllocSize += match.AllocSize
+			if err != nil {
+				return ageMatchMsg{match: total, err: err}
 			}
 		}
+		return ageMatchMsg{match: total}
 	}
 }
 
 func (m Model) cleanCmd() tea.Cmd {
-	action := m.confirmAction
+	clean := scanner.CleanOptions{Age: m.opts.ageFilter(), Strategy: cleaner.Delete}
+	if m.confirmAction == "trash" {
+		clean.Strategy = cleaner.Trash
+	}
+	opts := m.opts
+	selected := m.selectedEntries()
 	return func() tea.Msg {
-		var cleaned int64
-		var toClean []string
+		s, err := opts.newScanner()
+		if err != nil {
+			return cleanCompleteMsg{err: err}
+		}
 
-		// Collect paths to clean
-		for _, entry := range m.entries {
-			if entry.Selected {
-				toClean = append(toClean, entry.Path)
-				cleaned += entry.Size
-			} else {
-				for _, child := range entry.Children {
-					if child.Selected {
-						toClean = append(toClean, child.Path)
-						cleaned += child.Size
-					}
-				}
-			}
+		// Each module cleans its own entries
+		start := time.Now()
+		report := &cleaner.Report{}
+		for _, g := range byModule(selected) {
+			report.Merge(g.module.Clean(context.Background(), s, g.entries, clean))
 		}
+		report.Duration = time.Since(start)
+		return cleanCompleteMsg{report: report}
+	}
+}
 
-		// Clean each path
-		for _, path := range toClean {
-			var err error
-			if action == "trash" {
-				// Move to trash using AppleScript
-				script := fmt.Sprintf(`tell app "Finder" to delete POSIX file "%s"`, path)
-				cmd := exec.Command("osascript", "-e", script)
-				err = cmd.Run()
-			} else {
-				// Permanent delete
-				err = os.RemoveAll(path)
-			}
-			if err != nil {
-				return cleanCompleteMsg{err: fmt.Errorf("failed to clean %s: %v", path, err)}
-			}
+// untrashable returns the name of a selected entry whose module can't move
+// it to the trash, if there is one
+func (m Model) untrashable() (string, bool) {
+	var name string
+	m.eachSelected(func(e, target *scanner.CacheEntry) {
+		if name == "" && !scanner.CanTrash(e.Path) {
+			name = cmp.Or(target.Description, e.Name)
 		}
+	})
+	return name, name != ""
+}
+
+// cleanedText sums up a cleaning run. Trashed files still take up space, so
+// they're reported as moved rather than freed.
+func cleanedText(r *cleaner.Report) string {
+	freed, moved := r.Freed(), r.Moved()
+	switch {
+	case moved > 0 && freed > 0:
+		return fmt.Sprintf("Freed %s and moved %s to the Trash", scanner.FormatSize(freed), scanner.FormatSize(moved))
+	case moved > 0:
+		return fmt.Sprintf("Moved %s to the Trash", scanner.FormatSize(moved))
+	}
+	return fmt.Sprintf("Cleaned %s", scanner.FormatSize(freed))
+}
+
+// moduleEntries are the selected entries of one module
+type moduleEntries struct {
+	module  scanner.Module
+	entries []*scanner.CacheEntry
+}
 
-		return cleanCompleteMsg{cleaned: cleaned}
+// byModule groups entries by the module they came from, in module order
+func byModule(entries []*scanner.CacheEntry) []moduleEntries {
+	var groups []moduleEntries
+	for _, mod := range scanner.Modules() {
+		g := moduleEntries{module: mod}
+		for _, e := range entries {
+			if scanner.ModuleFor(e.Path) == mod {
+				g.entries = append(g.entries, e)
+			}
+		}
+		if len(g.entries) > 0 {
+			groups = append(groups, g)
+		}
 	}
+	return groups
 }
 
 func (m Model) View() string {
@@ -483,8 +996,49 @@ func (m Model) View() string {
 }
 
 func (m Model) viewScanning() string {
-	content := fmt.Sprintf("\n\n   %s Scanning directories...\n\n", m.spinner.View())
-	return lipgloss.NewStyle().Foreground(colorText).Render(content)
+	var b strings.Builder
+	p := m.scanProgress
+
+	b.WriteString(fmt.Sprintf("\n\n   %s Scanning directories...\n\n", m.spinner.View()))
+
+	if p.TargetsTotal > 0 {
+		b.WriteString(fmt.Sprintf("   %s  %d/%d targets\n",
+			m.renderProgressBar(p.TargetsDone, p.TargetsTotal, 30), p.TargetsDone, p.TargetsTotal))
+		b.WriteString(fmt.Sprintf("   %s  •  %s counted\n",
+			lipgloss.NewStyle().Foreground(colorSapphire).Render(fmt.Sprintf("%d files", p.FilesSeen)),
+			lipgloss.NewStyle().Foreground(colorBlue).Render(scanner.FormatSize(p.BytesCounted))))
+		if p.Current != "" && p.TargetsDone < p.TargetsTotal {
+			b.WriteString("   " + pathStyle.Render(scanner.ShortenPath(p.Current)) + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// Finished targets, most recent last, trimmed to fit the window
+	rows := m.scanRows
+	if maxRows := m.height - 12; maxRows > 0 && len(rows) > maxRows {
+		rows = rows[len(rows)-maxRows:]
+	}
+	for _, e := range rows {
+		name := e.Name
+		if len(name) > 20 {
+			name = name[:17] + "..."
+		}
+		check := lipgloss.NewStyle().Foreground(colorGreen).Render("✓")
+		b.WriteString(fmt.Sprintf("   %s %-20s  %10s  %s\n",
+			check, name, m.colorSize(m.sizeOf(e)), dimStyle.Render(e.Description)))
+	}
+
+	return lipgloss.NewStyle().Foreground(colorText).Render(b.String())
+}
+
+// renderProgressBar draws a done/total bar of the given width
+func (m Model) renderProgressBar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	return lipgloss.NewStyle().Foreground(colorMauve).Render(strings.Repeat("█", filled)) +
+		lipgloss.NewStyle().Foreground(colorSurface1).Render(strings.Repeat("░", width-filled))
 }
 
 func (m Model) viewList() string {
@@ -493,16 +1047,23 @@ func (m Model) viewList() string {
 	// Title
 	title := titleStyle.Render("  Dusty")
 	subtitle := dimStyle.Render("  Clean up your Mac")
+	profile := m.opts.Profile
+	if profile == "" {
+		profile = scanner.ProfileFull
+	}
+	subtitle += "  " + headerStyle.Render("Profile: "+profile)
 	b.WriteString(title + "\n" + subtitle + "\n\n")
+	b.WriteString(m.breadcrumbs() + "\n\n")
 
 	// Error display
 	if m.err != nil {
-		b.WriteString(confirmStyle.Render(fmt.Sprintf("  Error: %v", m.err)) + "\n\n")
+		msg := strings.ReplaceAll(m.err.Error(), "\n", "\n  ")
+		b.WriteString(confirmStyle.Render("  Error: "+msg) + "\n\n")
 	}
 
 	// Success message
 	if m.message != "" {
-		b.WriteString(successStyle.Render("  " + m.message) + "\n\n")
+		b.WriteString(successStyle.Render("  "+m.message) + "\n\n")
 	}
 
 	// Entries
@@ -511,7 +1072,7 @@ func (m Model) viewList() string {
 	}
 
 	// Calculate visible range
-	visibleHeight := m.height - 14
+	visibleHeight := m.height - 19
 	if visibleHeight < 5 {
 		visibleHeight = 5
 	}
@@ -529,9 +1090,9 @@ func (m Model) viewList() string {
 		e := de.entry
 
 		var line string
-		if de.isChild {
+		if de.depth > 0 {
 			// Child item - indented
-			line = m.renderChildItem(e, i == m.cursor)
+			line = m.renderChildItem(e, de.depth, i == m.cursor)
 		} else {
 			// Parent item
 			line = m.renderParentItem(e, i == m.cursor)
@@ -543,20 +1104,41 @@ func (m Model) viewList() string {
 	// Status bar
 	b.WriteString("\n")
 
+	// Age breakdown of the entry under the cursor
+	if m.cursor < len(m.displayList) {
+		b.WriteString(m.renderAges(m.displayList[m.cursor].entry) + "\n")
+	}
+
 	// Stats line
-	statsLine := fmt.Sprintf("  Total: %s  │  Selected: %s  │  Items: %d",
-		lipgloss.NewStyle().Foreground(colorBlue).Render(scanner.FormatSize(m.totalSize)),
+	total, sizeMode := m.totalSize, "apparent"
+	if m.showAlloc {
+		total, sizeMode = m.totalAlloc, "on disk"
+	}
+	statsLine := fmt.Sprintf("  Total: %s  │  Selected: %s  │  Items: %d  │  Sizes: %s",
+		lipgloss.NewStyle().Foreground(colorBlue).Render(scanner.FormatSize(total)),
 		lipgloss.NewStyle().Foreground(colorPink).Render(scanner.FormatSize(m.selectedSize)),
-		len(m.displayList))
+		len(m.displayList),
+		lipgloss.NewStyle().Foreground(colorTeal).Render(sizeMode))
 
+	if m.watchCh != nil {
+		statsLine += "  │  " + lipgloss.NewStyle().Foreground(colorGreen).Render("● watching")
+	}
+	if m.scanCh != nil && m.scanProgress.TargetsTotal > 0 {
+		statsLine += fmt.Sprintf("  │  %s Sizing %d/%d", m.spinner.View(),
+			m.scanProgress.TargetsDone, m.scanProgress.TargetsTotal)
+	}
 	if m.filter != "" {
 		statsLine += fmt.Sprintf("  │  Filter: %s", lipgloss.NewStyle().Foreground(colorYellow).Render(m.filter))
 	}
+	if age := m.opts.ageFilter(); age.Active() {
+		statsLine += fmt.Sprintf("  │  Clean: %s", lipgloss.NewStyle().Foreground(colorPeach).Render(
+			fmt.Sprintf("%s > %dd", age.Basis, m.opts.OlderThanDays)))
+	}
 
 	b.WriteString(statusStyle.Render(statsLine) + "\n\n")
 
 	// Help
-	help := "  ↑↓ navigate • space select • enter expand • a/A all/none • 🗑️ t trash • 💀 c clean • 🔄 r rescan • 🔍 / filter • ❓ ? help • 👋 q quit"
+	help := "  ↑↓ navigate • space select • enter expand • l/h open/up • [/] back/fwd • a/A all/none • f largest files • D duplicates • P projects • d disk/apparent • p profile • w watch • o/O age • 🗑️ t trash • 💀 c clean • 🔄 r rescan • 🔍 / filter • ❓ ? help • 👋 q quit"
 	b.WriteString(helpStyle.Render(help) + "\n")
 
 	return b.String()
@@ -571,20 +1153,14 @@ func (m Model) renderParentItem(e *scanner.CacheEntry, isCursor bool) string {
 	checkbox := dimStyle.Render("[ ]")
 	if e.Selected {
 		checkbox = lipgloss.NewStyle().Foreground(colorRed).Render("[✓]")
+	} else if e.Excluded {
+		checkbox = excludedStyle.Render("[-]")
 	}
 
-	// Expand/collapse icon
-	icon := "  "
-	if e.IsParent {
-		if e.Expanded {
-			icon = collapseIcon + " "
-		} else {
-			icon = expandIcon + " "
-		}
-	}
+	icon := m.expandIcon(e)
 
 	// Size with color
-	sizeStr := m.colorSize(e.Size)
+	sizeStr := m.colorSize(m.sizeOf(e))
 
 	// Name and description
 	name := e.Name
@@ -596,15 +1172,18 @@ func (m Model) renderParentItem(e *scanner.CacheEntry, isCursor bool) string {
 	path := scanner.ShortenPath(e.Path)
 
 	// File count and date with colors
-	files := lipgloss.NewStyle().Foreground(colorSapphire).Render(fmt.Sprintf("%d files", e.FileCount))
+	files := fileCount(e)
 	date := lipgloss.NewStyle().Foreground(colorLavender).Render(e.LastMod.Format("Jan 02"))
 
 	// Build the line
-	line := fmt.Sprintf("%s%s %s%-20s  %10s  %12s  %s",
-		cursor, checkbox, icon, name, sizeStr, files, date)
+	line := fmt.Sprintf("%s%s %s%-20s  %10s  %12s  %s%s",
+		cursor, checkbox, icon, name, sizeStr, files, date, entryMarks(e))
 
 	// Second line with path
 	pathLine := fmt.Sprintf("       %s", pathStyle.Render(path))
+	if a, ok := m.projects[e]; ok {
+		pathLine += m.artifactNotes(a)
+	}
 
 	if isCursor {
 		return selectedStyle.Render(line) + "\n" + pathLine
@@ -612,11 +1191,12 @@ func (m Model) renderParentItem(e *scanner.CacheEntry, isCursor bool) string {
 	return normalStyle.Render(line) + "\n" + pathLine
 }
 
-func (m Model) renderChildItem(e *scanner.CacheEntry, isCursor bool) string {
+func (m Model) renderChildItem(e *scanner.CacheEntry, depth int, isCursor bool) string {
 	cursor := "    "
 	if isCursor {
 		cursor = "  👉"
 	}
+	cursor += strings.Repeat("  ", depth-1)
 
 	checkbox := dimStyle.Render("[ ]")
 	if e.Selected {
@@ -624,7 +1204,7 @@ func (m Model) renderChildItem(e *scanner.CacheEntry, isCursor bool) string {
 	}
 
 	// Size with color
-	sizeStr := m.colorSize(e.Size)
+	sizeStr := m.colorSize(m.sizeOf(e))
 
 	// Name
 	name := e.Name
@@ -633,11 +1213,28 @@ func (m Model) renderChildItem(e *scanner.CacheEntry, isCursor bool) string {
 	}
 
 	// File count and date with colors
-	files := lipgloss.NewStyle().Foreground(colorSapphire).Render(fmt.Sprintf("%d files", e.FileCount))
+	files := fileCount(e)
 	date := lipgloss.NewStyle().Foreground(colorLavender).Render(e.LastMod.Format("Jan 02"))
 
-	line := fmt.Sprintf("%s%s   %-25s  %10s  %12s  %s",
-		cursor, checkbox, name, sizeStr, files, date)
+	// Excluded items are greyed out: shown for their size, never cleaned
+	if e.Excluded {
+		line := fmt.Sprintf("%s%s   %-25s  %10s  %12s",
+			cursor, "[-]", name, scanner.FormatSize(m.sizeOf(e)), "excluded")
+		if isCursor {
+			return selectedStyle.Render(line)
+		}
+		return excludedStyle.Render(line)
+	}
+
+	line := fmt.Sprintf("%s%s %s%-25s  %10s  %12s  %s%s",
+		cursor, checkbox, m.expandIcon(e), name, sizeStr, files, date, entryMarks(e))
+	if m.list == listDuplicates {
+		// Copies mostly share a name; where they are tells them apart
+		line += "  " + scanner.ShortenPath(filepath.Dir(e.Path))
+	} else if e.Description != "" {
+		// A target nested inside another
+		line += "  " + e.Description
+	}
 
 	if isCursor {
 		return selectedStyle.Render(line)
@@ -645,6 +1242,86 @@ func (m Model) renderChildItem(e *scanner.CacheEntry, isCursor bool) string {
 	return dimStyle.Render(line)
 }
 
+// ageColors go from fresh to stale, one per age bucket
+var ageColors = [scanner.NumAgeBuckets]lipgloss.Color{colorGreen, colorTeal, colorYellow, colorPeach, colorRed}
+
+// renderAges draws a bar of e's bytes split by age, with a legend below
+func (m Model) renderAges(e *scanner.CacheEntry) string {
+	const width = 40
+	var total int64
+	for _, bucket := range e.Ages {
+		total += bucket.Size
+	}
+	if total == 0 {
+		return dimStyle.Render("  Age: no files") + "\n"
+	}
+
+	var bar, legend strings.Builder
+	drawn := 0
+	var sum int64
+	for i, bucket := range e.Ages {
+		// Cumulative rounding keeps the bar exactly width cells wide
+		sum += bucket.Size
+		cells := int(sum*width/total) - drawn
+		drawn += cells
+		style := lipgloss.NewStyle().Foreground(ageColors[i])
+		bar.WriteString(style.Render(strings.Repeat("█", cells)))
+		if bucket.Size > 0 {
+			legend.WriteString("  " + style.Render("■") + dimStyle.Render(fmt.Sprintf(" %s %s",
+				scanner.AgeBucketLabels[i], scanner.FormatSize(bucket.Size))))
+		}
+	}
+	return "  Age " + bar.String() + "\n" + legend.String()
+}
+
+// expandIcon shows whether e can be opened, and whether it is
+func (m Model) expandIcon(e *scanner.CacheEntry) string {
+	switch {
+	case m.loading[e]:
+		return m.spinner.View() + " "
+	case !canOpen(e) || m.list != listTree:
+		return "  "
+	case e.Expanded:
+		return collapseIcon + " "
+	default:
+		return expandIcon + " "
+	}
+}
+
+// entryMarks flags symlinks, and entries that free less than they take
+// because other hard links keep part of their data alive
+func entryMarks(e *scanner.CacheEntry) string {
+	var marks string
+	if e.IsSymlink {
+		marks += lipgloss.NewStyle().Foreground(colorTeal).Render("  ↪ " + e.LinkTarget)
+	}
+	if e.SharedSize > 0 {
+		marks += lipgloss.NewStyle().Foreground(colorPeach).Render("  🔗 shared")
+	}
+	if e.ExcludedSize > 0 {
+		marks += excludedStyle.Render(fmt.Sprintf("  ⊘ %s excluded", scanner.FormatSize(e.ExcludedSize)))
+	}
+	return marks
+}
+
+// fileCount renders the file count column, or "symlink" for links
+func fileCount(e *scanner.CacheEntry) string {
+	if e.Pending {
+		return dimStyle.Render("sizing...")
+	}
+	if e.IsSymlink {
+		return lipgloss.NewStyle().Foreground(colorTeal).Render("symlink")
+	}
+	unit := "file"
+	if !filepath.IsAbs(e.Path) {
+		unit = "item" // Images, containers and the like from a module
+	}
+	if e.FileCount != 1 {
+		unit += "s"
+	}
+	return lipgloss.NewStyle().Foreground(colorSapphire).Render(fmt.Sprintf("%d %s", e.FileCount, unit))
+}
+
 func (m Model) colorSize(size int64) string {
 	sizeStr := scanner.FormatSize(size)
 	switch {
@@ -676,31 +1353,35 @@ func (m Model) viewConfirm() string {
 
 	var count int
 	var items []string
-	for _, entry := range m.entries {
-		if entry.Selected {
-			count++
-			items = append(items, fmt.Sprintf("  • %s (%s)\n    %s",
-				entry.Name,
-				scanner.FormatSize(entry.Size),
-				pathStyle.Render(scanner.ShortenPath(entry.Path))))
-		} else {
-			for _, child := range entry.Children {
-				if child.Selected {
-					count++
-					items = append(items, fmt.Sprintf("  • %s (%s)\n    %s",
-						child.Name,
-						scanner.FormatSize(child.Size),
-						pathStyle.Render(scanner.ShortenPath(child.Path))))
-				}
-			}
-		}
-	}
+	m.eachSelected(func(e, target *scanner.CacheEntry) {
+		count++
+		items = append(items, m.confirmItem(e, cmp.Or(e.Risk, target.Risk)))
+	})
 
 	for _, item := range items {
 		b.WriteString(normalStyle.Render(item) + "\n\n")
 	}
 
-	b.WriteString(confirmStyle.Render(fmt.Sprintf("  %s %d items (%s)?", actionText, count, scanner.FormatSize(m.selectedSize))))
+	// On-disk bytes are what the filesystem actually gets back
+	freed := m.selectedAlloc
+	if age := m.opts.ageFilter(); age.Active() {
+		b.WriteString(normalStyle.Render(fmt.Sprintf("  Only files with %s older than %d days, then empty folders:",
+			age.Basis, m.opts.OlderThanDays)) + "\n")
+		if m.ageMatch == nil {
+			b.WriteString(fmt.Sprintf("  %s Counting matching files...\n\n", m.spinner.View()))
+			b.WriteString(helpStyle.Render("  Press n to cancel") + "\n")
+			return b.String()
+		}
+		b.WriteString(normalStyle.Render(fmt.Sprintf("  %d files, %s", m.ageMatch.Files, scanner.FormatSize(m.ageMatch.Size))) + "\n\n")
+		freed = m.ageMatch.AllocSize
+	}
+
+	// Trashed files keep their space until the Trash is emptied
+	outcome := fmt.Sprintf("frees %s on disk", scanner.FormatSize(freed))
+	if m.confirmAction == "trash" {
+		outcome = fmt.Sprintf("moves %s to the Trash", scanner.FormatSize(freed))
+	}
+	b.WriteString(confirmStyle.Render(fmt.Sprintf("  %s %d items (%s)?", actionText, count, outcome)))
 	b.WriteString("\n\n")
 	b.WriteString(helpStyle.Render("  Press y to confirm, n to cancel"))
 	b.WriteString("\n")
@@ -708,6 +1389,27 @@ func (m Model) viewConfirm() string {
 	return b.String()
 }
 
+// confirmItem renders one entry of the confirm list, with the risk note of
+// the target it belongs to
+func (m Model) confirmItem(e *scanner.CacheEntry, risk string) string {
+	item := fmt.Sprintf("  • %s (%s)\n    %s",
+		e.Name,
+		scanner.FormatSize(m.sizeOf(e)),
+		pathStyle.Render(scanner.ShortenPath(e.Path)))
+	if risk != "" {
+		item += "\n    " + dimStyle.Render("⚠ "+risk)
+	}
+	if e.SharedSize > 0 {
+		item += "\n    " + lipgloss.NewStyle().Foreground(colorPeach).Render(
+			fmt.Sprintf("🔗 %s stays on disk: hard-linked from elsewhere", scanner.FormatSize(e.SharedSize)))
+	}
+	if a, ok := m.projects[e]; ok && a.Dirty {
+		item += "\n    " + lipgloss.NewStyle().Foreground(colorPeach).Render(
+			fmt.Sprintf("⚠ %s has uncommitted changes", scanner.ShortenPath(a.Repo)))
+	}
+	return item
+}
+
 func (m Model) viewHelp() string {
 	var b strings.Builder
 
@@ -718,15 +1420,27 @@ func (m Model) viewHelp() string {
 		desc string
 	}{
 		{"↑/k, ↓/j", "Navigate up/down"},
-		{"Enter/l", "Expand/collapse"},
+		{"Enter", "Expand/collapse"},
+		{"l/→", "Open directory"},
+		{"h/←", "Up to parent directory"},
+		{"[ / ]", "Back / forward"},
 		{"Space", "Toggle selection"},
 		{"a", "Select all"},
 		{"A", "Deselect all"},
+		{"d", "Toggle on-disk / apparent sizes"},
+		{"f", "Show the largest files / the tree"},
+		{"D", "Show duplicate files / the tree"},
+		{"P", "Show build artifacts in projects / the tree"},
+		{"p", "Switch profile and rescan"},
+		{"w", "Watch targets and update sizes live"},
+		{"o", "Clean only files older than 7/30/90/180 days"},
+		{"O", "Judge age by mtime / atime"},
 		{"t", "🗑️  Move to Trash"},
 		{"c", "💀 Clean (permanent)"},
 		{"r", "🔄 Rescan directories"},
+		{"R", "Clear the scan cache and rescan everything"},
 		{"/", "🔍 Filter items"},
-		{"Esc", "Clear filter"},
+		{"Esc", "Clear filter / cancel scan"},
 		{"?", "❓ Show this help"},
 		{"q", "👋 Quit"},
 	}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/han-nwin/dusty/cleaner"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// deleteOnly is a module that can't move its entries to the trash, like
+// Docker
+type deleteOnly struct{}
+
+func (deleteOnly) Name() string                                     { return "delete-only" }
+func (deleteOnly) Available(context.Context, *scanner.Scanner) bool { return false }
+func (deleteOnly) Owns(path string) bool                            { return strings.HasPrefix(path, "deleteonly:") }
+func (deleteOnly) Scan(context.Context, *scanner.Scanner) (*scanner.ScanResult, error) {
+	return nil, nil
+}
+func (deleteOnly) Clean(_ context.Context, _ *scanner.Scanner, entries []*scanner.CacheEntry, _ scanner.CleanOptions) *cleaner.Report {
+	return scanner.RefuseTrash(entries)
+}
+
+func init() {
+	scanner.Register(deleteOnly{})
+}
+
+// listModel returns a model showing entries, scan done
+func listModel(entries ...*scanner.CacheEntry) Model {
+	m := NewModel(Options{})
+	m.state = viewList
+	m.entries = entries
+	m.rebuildDisplayList()
+	m.updateSelectedSize()
+	return m
+}
+
+func press(m Model, key string) Model {
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return next.(Model)
+}
+
+func TestTrashRefusesEntriesThatCanOnlyBeDeleted(t *testing.T) {
+	images := entry("deleteonly:images", 500, true)
+	images.Description = "Images"
+	m := press(listModel(entry("/h/.cache", 1000, true), images), "t")
+	if m.state != viewList || !strings.Contains(m.message, "Images can't be moved to the Trash") {
+		t.Errorf("state %v, message %q; want the trash refused for Images", m.state, m.message)
+	}
+
+	images.Selected = false
+	m = press(listModel(entry("/h/.cache", 1000, true), images), "t")
+	if m.state != viewConfirm {
+		t.Errorf("state %v, want the trash confirmed once Images is deselected", m.state)
+	}
+}
+
+func TestConfirmTrashSaysMovedNotFreed(t *testing.T) {
+	m := press(listModel(entry("/h/.cache", 2048, true)), "t")
+	view := m.viewConfirm()
+	if !strings.Contains(view, "moves 2.0 KB to the Trash") || strings.Contains(view, "frees") {
+		t.Errorf("trash prompt:\n%s\nwant it to say the bytes are moved, not freed", view)
+	}
+
+	m = press(listModel(entry("/h/.cache", 2048, true)), "c")
+	if view := m.viewConfirm(); !strings.Contains(view, "frees 2.0 KB on disk") {
+		t.Errorf("clean prompt:\n%s\nwant it to say the bytes are freed", view)
+	}
+}
+
+func TestConfirmShowsSelectionTotals(t *testing.T) {
+	vm := entry("/h/.cache/vm", 8<<20, true)
+	vm.Name, vm.AllocSize = "vm", 2<<20
+	store := entry("/h/.cache/store", 1<<20, true, entry("/h/.cache/store/x", 1<<20, true))
+	store.Name, store.AllocSize, store.SharedSize = "store", 4<<20, 1<<20
+	m := press(listModel(vm, store, entry("/h/idle", 300, false)), "c")
+
+	view := m.viewConfirm()
+	for _, want := range []string{
+		"vm (8.0 MB)", "store (1.0 MB)", // Apparent sizes, as listed
+		"1.0 MB stays on disk",
+		"Clean 2 items (frees 5.0 MB on disk)", // What the filesystem gets back
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("confirm prompt:\n%s\nwant %q", view, want)
+		}
+	}
+
+	m = press(press(m, "n"), "d")
+	if view := press(m, "c").viewConfirm(); !strings.Contains(view, "vm (2.0 MB)") || !strings.Contains(view, "frees 5.0 MB on disk") {
+		t.Errorf("confirm prompt in on-disk mode:\n%s\nwant on-disk sizes and the same total", view)
+	}
+}
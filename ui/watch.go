@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/han-nwin/dusty/scanner"
+)
+
+// watchCmd starts following changes to roots in the background. Changes
+// arrive on the channel carried by watchStartedMsg.
+func watchCmd(opts Options, roots []string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan tea.Msg, 16)
+		go runWatch(ctx, opts, roots, ch)
+		return watchStartedMsg{ch: ch, cancel: cancel}
+	}
+}
+
+func runWatch(ctx context.Context, opts Options, roots []string, ch chan<- tea.Msg) {
+	defer close(ch)
+	s, err := opts.newScanner()
+	if err != nil {
+		ch <- watchStoppedMsg{err: err}
+		return
+	}
+	if !opts.NoCache {
+		s.Cache = s.LoadCache()
+	}
+	err = s.Watch(ctx, roots, func(changes []scanner.Change) {
+		select {
+		case ch <- watchChangesMsg(changes):
+		case <-ctx.Done():
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	ch <- watchStoppedMsg{err: err}
+}
+
+// waitForWatch delivers the next message from the running watch
+func waitForWatch(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return watchEventMsg{ch: ch, msg: msg}
+	}
+}
+
+// startWatch follows changes to the listed targets
+func (m *Model) startWatch() tea.Cmd {
+	m.stopWatch()
+	var roots []string
+	for _, e := range m.entries {
+		roots = append(roots, e.Path)
+	}
+	return watchCmd(m.opts, roots)
+}
+
+// stopWatch stops the running watch, if any
+func (m *Model) stopWatch() {
+	if m.cancelWatch != nil {
+		m.cancelWatch()
+		m.cancelWatch = nil
+	}
+	m.watchCh = nil
+}
+
+func (m Model) handleWatchEvent(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case watchChangesMsg:
+		for i := range msg {
+			applyChange(m.entries, &msg[i])
+			m.dropLargest(&msg[i])
+		}
+		m.totalSize, m.totalAlloc = 0, 0
+		for _, e := range m.entries {
+			m.totalSize += e.Size
+			m.totalAlloc += e.AllocSize
+		}
+		m.refreshList()
+		return m, waitForWatch(m.watchCh)
+
+	case watchStoppedMsg:
+		m.stopWatch()
+		if msg.err != nil {
+			m.opts.Watch = false
+			m.err = msg.err
+		}
+	}
+	return m, nil
+}
+
+// dropLargest forgets the largest files c reports as gone
+func (m *Model) dropLargest(c *scanner.Change) {
+	m.largest = slices.DeleteFunc(m.largest, func(e *scanner.CacheEntry) bool {
+		return filepath.Dir(e.Path) == c.Dir && slices.Contains(c.Removed, e.Name)
+	})
+}
+
+// applyChange adds c to every entry containing its directory, and updates
+// the listing of the directory itself if it has one
+func applyChange(entries []*scanner.CacheEntry, c *scanner.Change) {
+	for _, e := range entries {
+		if !c.Affects(e) {
+			continue
+		}
+		c.Apply(e)
+		if e.Path == c.Dir && e.Listed {
+			updateListing(e, c)
+			continue
+		}
+		applyChange(e.Children, c)
+	}
+}
+
+// updateListing replaces the children c reports on, keeping what the user
+// did with them, and drops the ones that are gone or empty
+func updateListing(e *scanner.CacheEntry, c *scanner.Change) {
+	byPath := make(map[string]*scanner.CacheEntry, len(e.Children))
+	for _, child := range e.Children {
+		byPath[child.Path] = child
+	}
+	for _, u := range c.Updated {
+		u.Depth = e.Depth + 1
+		if child, ok := byPath[u.Path]; ok {
+			u.Selected, u.Expanded = child.Selected, child.Expanded
+			if child.Listed {
+				u.Children, u.Listed = child.Children, true
+			}
+			*child = *u
+			continue
+		}
+		u.Selected = e.Selected && !u.Excluded
+		e.Children = append(e.Children, u)
+		byPath[u.Path] = u
+	}
+
+	var kept []*scanner.CacheEntry
+	for _, child := range e.Children {
+		if child.Size > 0 && !slices.Contains(c.Removed, child.Name) {
+			kept = append(kept, child)
+		}
+	}
+	e.Children = kept
+}